@@ -0,0 +1,73 @@
+package rainbow
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/nerdwave-nick/rainbow/ansi"
+)
+
+const defaultMaxKeyWidth = 24
+
+// keyColumn finds the widest key of a record, group prefixes or tree
+// guides included, leaving out the keys wider than the maximum
+func (h *TextHandler) keyColumn(r slog.Record, hs *handleState) int {
+	widest := 0
+	visit := func(width int) {
+		if width <= h.maxKeyWidth {
+			widest = max(widest, width)
+		}
+	}
+	for _, ca := range hs.Context {
+		h.keyWidths(ca.Groups, ca.Attrs, visit)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		a.Value = a.Value.Resolve()
+		h.keyWidths(hs.Groups, []slog.Attr{a}, visit)
+		return true
+	})
+	return widest
+}
+
+func (h *TextHandler) keyWidths(groups []string, attrs []slog.Attr, visit func(int)) {
+	for _, a := range attrs {
+		if a.Equal(slog.Attr{}) {
+			continue
+		}
+		if a.Value.Kind() != slog.KindGroup {
+			visit(h.prefixWidth(groups) + ansi.Width(a.Key))
+			continue
+		}
+		if a.Key == "" {
+			h.keyWidths(groups, a.Value.Group(), visit)
+			continue
+		}
+		h.keyWidths(append(groups[:len(groups):len(groups)], a.Key), a.Value.Group(), visit)
+	}
+}
+
+// prefixWidth is the width of what comes before a key in the given groups,
+// the dotted group names or the guides of the tree
+func (h *TextHandler) prefixWidth(groups []string) int {
+	if h.groupStyle != GroupStyleDotted {
+		// every level of guides is three wide
+		return 3 * len(groups)
+	}
+	width := 0
+	for _, g := range groups {
+		width += ansi.Width(g) + 1
+	}
+	return width
+}
+
+// appendKeyPad pads a key out to the key column of the record
+func (h *TextHandler) appendKeyPad(buf []byte, key string, hs *handleState) []byte {
+	if hs.KeyWidth == 0 {
+		return buf
+	}
+	n := hs.KeyWidth - h.prefixWidth(hs.Groups) - ansi.Width(key)
+	if n <= 0 {
+		return buf
+	}
+	return append(buf, strings.Repeat(" ", n)...)
+}
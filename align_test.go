@@ -0,0 +1,82 @@
+package rainbow_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/nerdwave-nick/rainbow"
+	"github.com/nerdwave-nick/rainbow/ansi"
+)
+
+func TestRainbow_AlignKeys(t *testing.T) {
+	attrs := []slog.Attr{
+		slog.Group("http", slog.String("method", "GET"), slog.Int("status", 200)),
+		slog.Int("a_really_long_key_name", 1),
+	}
+	tests := []struct {
+		GroupStyle  rainbow.GroupStyle
+		MaxKeyWidth int
+		Output      string
+	}{
+		{
+			Output: "|INF m\n" +
+				"\tn              =1\n" +
+				"\tjob.id         =7\n" +
+				"\tjob.http.method=\"GET\"\n" +
+				"\tjob.http.status=200\n" +
+				"\tjob.a_really_long_key_name=1\n",
+		},
+		{
+			MaxKeyWidth: 10,
+			Output: "|INF m\n" +
+				"\tn     =1\n" +
+				"\tjob.id=7\n" +
+				"\tjob.http.method=\"GET\"\n" +
+				"\tjob.http.status=200\n" +
+				"\tjob.a_really_long_key_name=1\n",
+		},
+		{
+			GroupStyle: rainbow.GroupStyleTree,
+			Output: "|INF m\n" +
+				"\tn           =1\n" +
+				"\tjob\n" +
+				"\t├─ id       =7\n" +
+				"\t├─ http\n" +
+				"\t│  ├─ method=\"GET\"\n" +
+				"\t│  └─ status=200\n" +
+				"\t└─ a_really_long_key_name=1\n",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("align keys test %d", i), func(t *testing.T) {
+			buffer := bytes.NewBuffer(make([]byte, 0))
+			h := rainbow.New(buffer, &rainbow.Options{
+				AlignKeys:   true,
+				MaxKeyWidth: tt.MaxKeyWidth,
+				GroupStyle:  tt.GroupStyle,
+			}).WithAttrs([]slog.Attr{slog.Int("n", 1)}).WithGroup("job").WithAttrs([]slog.Attr{slog.Int("id", 7)})
+			handleNoTime(t, h, slog.LevelInfo, "m", attrs...)
+			// the padding goes by what is visible
+			if actual := ansi.Strip(buffer.String()); actual != tt.Output {
+				t.Errorf("output %q did not match the expected output %q", actual, tt.Output)
+			}
+		})
+	}
+}
+
+func TestRainbow_AlignWideKeys(t *testing.T) {
+	buffer := bytes.NewBuffer(make([]byte, 0))
+	h := rainbow.New(buffer, &rainbow.Options{AlignKeys: true})
+	handleNoTime(t, h, slog.LevelInfo, "m", slog.Int("名前", 1), slog.Int("e\u0301te\u0301", 2), slog.Int("id", 3))
+	// wide keys take two columns a character, combining marks none
+	expected := "|INF m\n" +
+		"\t名前=1\n" +
+		"\te\u0301te\u0301 =2\n" +
+		"\tid  =3\n"
+	if actual := ansi.Strip(buffer.String()); actual != expected {
+		t.Errorf("output %q did not match the expected output %q", actual, expected)
+	}
+}
@@ -1,6 +1,7 @@
 package rainbow
 
 import (
+	"fmt"
 	"strings"
 )
 
@@ -119,3 +120,126 @@ func Mod(attrs ...AnsiAttr) AnsiMod {
 	sb.WriteString("m")
 	return AnsiMod(sb.String())
 }
+
+var modNames = map[string]AnsiAttr{
+	"reset":      Fmt.Reset,
+	"bold":       Fmt.Bold,
+	"faint":      Fmt.Faint,
+	"italic":     Fmt.Italic,
+	"underline":  Fmt.Underline,
+	"blink":      Fmt.Blink,
+	"crossedout": Fmt.CrossedOut,
+
+	"black":     Fg.Black,
+	"red":       Fg.Red,
+	"green":     Fg.Green,
+	"yellow":    Fg.Yellow,
+	"blue":      Fg.Blue,
+	"magenta":   Fg.Magenta,
+	"cyan":      Fg.Cyan,
+	"white":     Fg.White,
+	"hiblack":   Fg.HiBlack,
+	"hired":     Fg.HiRed,
+	"higreen":   Fg.HiGreen,
+	"hiyellow":  Fg.HiYellow,
+	"hiblue":    Fg.HiBlue,
+	"himagenta": Fg.HiMagenta,
+	"hicyan":    Fg.HiCyan,
+	"hiwhite":   Fg.HiWhite,
+
+	"bgblack":     Bg.Black,
+	"bgred":       Bg.Red,
+	"bggreen":     Bg.Green,
+	"bgyellow":    Bg.Yellow,
+	"bgblue":      Bg.Blue,
+	"bgmagenta":   Bg.Magenta,
+	"bgcyan":      Bg.Cyan,
+	"bgwhite":     Bg.White,
+	"bghiblack":   Bg.HiBlack,
+	"bghired":     Bg.HiRed,
+	"bghigreen":   Bg.HiGreen,
+	"bghiyellow":  Bg.HiYellow,
+	"bghiblue":    Bg.HiBlue,
+	"bghimagenta": Bg.HiMagenta,
+	"bghicyan":    Bg.HiCyan,
+	"bghiwhite":   Bg.HiWhite,
+}
+
+// ParseMod builds a mod from attribute names like "bold red" or
+// "hi-cyan, bg-black", as used in config files. Names are case
+// insensitive, dashes and underscores are ignored. Strings that
+// already start with an escape are returned as they are.
+func ParseMod(s string) (AnsiMod, error) {
+	if strings.HasPrefix(s, string(escape)) {
+		return AnsiMod(s), nil
+	}
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ' ' || r == ',' || r == '+'
+	})
+	attrs := make([]AnsiAttr, 0, len(fields))
+	for _, f := range fields {
+		name := strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(f))
+		attr, ok := modNames[name]
+		if !ok {
+			return "", fmt.Errorf("rainbow: unknown ansi attribute %q", f)
+		}
+		attrs = append(attrs, attr)
+	}
+	return Mod(attrs...), nil
+}
+
+func (m *AnsiMod) UnmarshalText(text []byte) error {
+	mod, err := ParseMod(string(text))
+	if err != nil {
+		return err
+	}
+	*m = mod
+	return nil
+}
+
+// attrs splits a mod made of a single SGR sequence into its attributes,
+// false if it is anything else
+func (m AnsiMod) attrs() ([]AnsiAttr, bool) {
+	s, ok := strings.CutPrefix(string(m), string(escape)+"[")
+	if !ok {
+		return nil, false
+	}
+	s, ok = strings.CutSuffix(s, "m")
+	if !ok || strings.ContainsAny(s, "\x1bm") {
+		return nil, false
+	}
+	attrs := []AnsiAttr{}
+	for _, a := range strings.Split(s, ";") {
+		attrs = append(attrs, AnsiAttr(a))
+	}
+	return attrs, true
+}
+
+// MarshalText writes the mod as attribute names, the way ParseMod reads
+// them, if it only uses attributes with names
+func (m AnsiMod) MarshalText() ([]byte, error) {
+	if m == "" {
+		return []byte{}, nil
+	}
+	attrs, ok := m.attrs()
+	if !ok {
+		return []byte(m), nil
+	}
+	names := make([]string, 0, len(attrs))
+	for _, a := range attrs {
+		name, ok := attrNames[a]
+		if !ok {
+			return []byte(m), nil
+		}
+		names = append(names, name)
+	}
+	return []byte(strings.Join(names, " ")), nil
+}
+
+var attrNames = func() map[AnsiAttr]string {
+	names := make(map[AnsiAttr]string, len(modNames))
+	for name, attr := range modNames {
+		names[attr] = name
+	}
+	return names
+}()
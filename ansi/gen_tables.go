@@ -0,0 +1,150 @@
+//go:build ignore
+
+// gen_tables writes tables.go, the width tables of RuneWidth.
+// The wide ranges come from EastAsianWidth.txt of the Unicode
+// Character Database, the zero width ones from package unicode.
+//
+//	go run gen_tables.go [-eaw EastAsianWidth.txt]
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+func main() {
+	eaw := flag.String("eaw", "https://www.unicode.org/Public/UCD/latest/ucd/EastAsianWidth.txt", "file or URL of EastAsianWidth.txt")
+	out := flag.String("o", "tables.go", "output file")
+	flag.Parse()
+
+	in, err := open(*eaw)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer in.Close()
+	version, wide, err := parseEastAsianWidth(in)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var zero []rune
+	for r := rune(0); r <= unicode.MaxRune; r++ {
+		if isZero(r) {
+			zero = append(zero, r)
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by go run gen_tables.go; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package ansi\n\n")
+	fmt.Fprintf(&buf, "// East Asian Width %s\n", version)
+	writeTable(&buf, "wideTable", ranges(wide))
+	fmt.Fprintf(&buf, "\n// Unicode %s\n", unicode.Version)
+	writeTable(&buf, "zeroTable", ranges(zero))
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func open(name string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(name, "http://") && !strings.HasPrefix(name, "https://") {
+		return os.Open(name)
+	}
+	res, err := http.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("get %s: %s", name, res.Status)
+	}
+	return res.Body, nil
+}
+
+// parseEastAsianWidth finds the wide and fullwidth runes, and the
+// version from the first line like # EastAsianWidth-15.1.0.txt
+func parseEastAsianWidth(r io.Reader) (string, []rune, error) {
+	version := "unknown"
+	var wide []rune
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if n == 1 && strings.HasPrefix(line, "# EastAsianWidth-") {
+			version = strings.TrimSuffix(strings.TrimPrefix(line, "# EastAsianWidth-"), ".txt")
+		}
+		line, _, _ = strings.Cut(line, "#")
+		codes, width, ok := strings.Cut(line, ";")
+		if !ok {
+			continue
+		}
+		width = strings.TrimSpace(width)
+		if width != "W" && width != "F" {
+			continue
+		}
+		from, to, isRange := strings.Cut(strings.TrimSpace(codes), "..")
+		if !isRange {
+			to = from
+		}
+		lo, err := strconv.ParseUint(from, 16, 32)
+		if err != nil {
+			return "", nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		hi, err := strconv.ParseUint(to, 16, 32)
+		if err != nil {
+			return "", nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		for r := rune(lo); r <= rune(hi); r++ {
+			wide = append(wide, r)
+		}
+	}
+	return version, wide, sc.Err()
+}
+
+// isZero are the runes that don't take up a column of their own:
+// combining marks, format characters and the Hangul vowels and
+// final consonants that join the syllable before them
+func isZero(r rune) bool {
+	switch {
+	case r == 0x00ad:
+		// the soft hyphen is shown by most terminals
+		return false
+	case r >= 0x1160 && r <= 0x11ff, r >= 0xd7b0 && r <= 0xd7ff:
+		return true
+	}
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf)
+}
+
+// ranges turns sorted runes into ranges of consecutive runes
+func ranges(runes []rune) [][2]rune {
+	var rs [][2]rune
+	for _, r := range runes {
+		if len(rs) > 0 && rs[len(rs)-1][1] == r-1 {
+			rs[len(rs)-1][1] = r
+			continue
+		}
+		rs = append(rs, [2]rune{r, r})
+	}
+	return rs
+}
+
+func writeTable(w io.Writer, name string, rs [][2]rune) {
+	fmt.Fprintf(w, "var %s = [][2]rune{\n", name)
+	for _, r := range rs {
+		fmt.Fprintf(w, "\t{0x%04x, 0x%04x},\n", r[0], r[1])
+	}
+	fmt.Fprintf(w, "}\n")
+}
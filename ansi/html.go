@@ -0,0 +1,311 @@
+package ansi
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+)
+
+type HTMLOptions struct {
+	// Classes writes CSS classes instead of inline styles,
+	// the rules for them come from Stylesheet
+	Classes bool
+	// ClassPrefix is put in front of every class, defaults to "rb-"
+	ClassPrefix string
+	// Roles gives styles a class of their own, e.g. the level or key
+	// styles of a theme, so they can be restyled in CSS
+	Roles map[Style]string
+	// Palette the 16 and 256 colors are shown in, DefaultPalette if nil
+	Palette *Palette
+	// Standalone wraps the output in a complete HTML document
+	Standalone bool
+	// Title of the standalone document
+	Title string
+}
+
+func (o *HTMLOptions) prefix() string {
+	if o.ClassPrefix == "" {
+		return "rb-"
+	}
+	return o.ClassPrefix
+}
+
+func (o *HTMLOptions) palette() *Palette {
+	if o.Palette == nil {
+		return &DefaultPalette
+	}
+	return o.Palette
+}
+
+// HTMLWriter turns colored text written to it into HTML, as it comes in.
+// Close has to be called to finish the document.
+type HTMLWriter struct {
+	w      io.Writer
+	opts   HTMLOptions
+	parser Parser
+	buf    []byte
+
+	started bool
+	open    bool
+	current Style
+	err     error
+}
+
+func NewHTMLWriter(w io.Writer, opts *HTMLOptions) *HTMLWriter {
+	if opts == nil {
+		opts = &HTMLOptions{}
+	}
+	return &HTMLWriter{w: w, opts: *opts}
+}
+
+func (h *HTMLWriter) Write(b []byte) (int, error) {
+	if h.err != nil {
+		return 0, h.err
+	}
+	h.buf = h.buf[:0]
+	h.start()
+	h.parser.Feed(b, h.writeRun)
+	if _, err := h.w.Write(h.buf); err != nil {
+		h.err = err
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Close ends the open span and the document, it doesn't close the
+// underlying writer
+func (h *HTMLWriter) Close() error {
+	if h.err != nil {
+		return h.err
+	}
+	h.buf = h.buf[:0]
+	h.start()
+	if h.open {
+		h.buf = append(h.buf, "</span>"...)
+		h.open = false
+	}
+	h.buf = append(h.buf, "</pre>"...)
+	if h.opts.Standalone {
+		h.buf = append(h.buf, "\n</body>\n</html>\n"...)
+	}
+	_, h.err = h.w.Write(h.buf)
+	return h.err
+}
+
+func (h *HTMLWriter) start() {
+	if h.started {
+		return
+	}
+	h.started = true
+	p := h.opts.palette()
+	if h.opts.Standalone {
+		h.buf = append(h.buf, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>"...)
+		h.buf = appendEscapedHTML(h.buf, []byte(h.opts.Title))
+		h.buf = append(h.buf, "</title>\n"...)
+		if h.opts.Classes {
+			h.buf = append(h.buf, "<style>\n"...)
+			h.buf = append(h.buf, Stylesheet(&h.opts)...)
+			h.buf = append(h.buf, "</style>\n"...)
+		}
+		h.buf = append(h.buf, "</head>\n<body>\n"...)
+	}
+	if h.opts.Classes {
+		h.buf = append(h.buf, "<pre class=\""...)
+		h.buf = append(h.buf, strings.TrimSuffix(h.opts.prefix(), "-")...)
+		h.buf = append(h.buf, "\">"...)
+		return
+	}
+	h.buf = append(h.buf, "<pre style=\"color:"...)
+	h.buf = append(h.buf, p.Foreground.Hex()...)
+	h.buf = append(h.buf, ";background-color:"...)
+	h.buf = append(h.buf, p.Background.Hex()...)
+	h.buf = append(h.buf, "\">"...)
+}
+
+func (h *HTMLWriter) writeRun(r Run) {
+	if !h.open || r.Style != h.current {
+		if h.open {
+			h.buf = append(h.buf, "</span>"...)
+			h.open = false
+		}
+		if !r.Style.IsZero() {
+			h.buf = h.appendSpan(h.buf, r.Style)
+			h.open = true
+		}
+		h.current = r.Style
+	}
+	h.buf = appendEscapedHTML(h.buf, r.Text)
+}
+
+func (h *HTMLWriter) appendSpan(buf []byte, s Style) []byte {
+	buf = append(buf, "<span"...)
+	if !h.opts.Classes {
+		buf = append(buf, " style=\""...)
+		buf = append(buf, inlineCSS(h.opts.palette(), s)...)
+		return append(buf, "\">"...)
+	}
+	prefix := h.opts.prefix()
+	if role, ok := h.opts.Roles[s]; ok {
+		buf = append(buf, " class=\""...)
+		buf = append(buf, prefix...)
+		buf = append(buf, role...)
+		return append(buf, "\">"...)
+	}
+	classes := flagClasses(prefix, s)
+	inline := Style{}
+	// reverse video and true colors have no class
+	if s.Has(FlagReverse) {
+		// faint comes from the class already
+		inline = s.Without(FlagFaint)
+	} else {
+		if c, ok := colorClass(prefix, "fg", s.Fg); ok {
+			classes = append(classes, c)
+		} else {
+			inline.Fg = s.Fg
+		}
+		if c, ok := colorClass(prefix, "bg", s.Bg); ok {
+			classes = append(classes, c)
+		} else {
+			inline.Bg = s.Bg
+		}
+		inline.UnderlineColor = s.UnderlineColor
+	}
+	if len(classes) > 0 {
+		buf = append(buf, " class=\""...)
+		buf = append(buf, strings.Join(classes, " ")...)
+		buf = append(buf, '"')
+	}
+	if css := colorCSS(h.opts.palette(), inline); css != "" {
+		buf = append(buf, " style=\""...)
+		buf = append(buf, css...)
+		buf = append(buf, '"')
+	}
+	return append(buf, '>')
+}
+
+var flagNames = [...]string{"bold", "faint", "italic", "underline", "blink", "reverse", "strike"}
+
+func flagClasses(prefix string, s Style) []string {
+	classes := make([]string, 0, 4)
+	for i, name := range flagNames {
+		if s.Flags&(1<<i) != 0 && StyleFlags(1<<i) != FlagReverse {
+			classes = append(classes, prefix+name)
+		}
+	}
+	return classes
+}
+
+// colorCSS has the colors of a style, without any of the flags
+// except the ones changing colors
+func colorCSS(p *Palette, s Style) string {
+	var sb strings.Builder
+	fg, bg, bgSet := p.colors(s)
+	if s.Fg.IsSet() || s.Has(FlagReverse) || s.Has(FlagFaint) {
+		sb.WriteString("color:")
+		sb.WriteString(fg.Hex())
+		sb.WriteString(";")
+	}
+	if bgSet {
+		sb.WriteString("background-color:")
+		sb.WriteString(bg.Hex())
+		sb.WriteString(";")
+	}
+	if c, ok := p.Resolve(s.UnderlineColor); ok {
+		sb.WriteString("text-decoration-color:")
+		sb.WriteString(c.Hex())
+		sb.WriteString(";")
+	}
+	return sb.String()
+}
+
+func inlineCSS(p *Palette, s Style) string {
+	css := colorCSS(p, s)
+	if s.Has(FlagBold) {
+		css += "font-weight:bold;"
+	}
+	if s.Has(FlagItalic) {
+		css += "font-style:italic;"
+	}
+	decorations := make([]string, 0, 3)
+	if s.Has(FlagUnderline) {
+		decorations = append(decorations, "underline")
+	}
+	if s.Has(FlagStrike) {
+		decorations = append(decorations, "line-through")
+	}
+	if s.Has(FlagBlink) {
+		decorations = append(decorations, "blink")
+	}
+	if len(decorations) > 0 {
+		css += "text-decoration-line:" + strings.Join(decorations, " ") + ";"
+	}
+	return css
+}
+
+// Stylesheet has the CSS rules for the classes written
+// when HTMLOptions.Classes is set
+func Stylesheet(opts *HTMLOptions) string {
+	if opts == nil {
+		opts = &HTMLOptions{}
+	}
+	p := opts.palette()
+	prefix := opts.prefix()
+	var sb strings.Builder
+	sb.WriteString("pre." + strings.TrimSuffix(prefix, "-") + " { color: " + p.Foreground.Hex() + "; background-color: " + p.Background.Hex() + "; }\n")
+	sb.WriteString("." + prefix + "bold { font-weight: bold; }\n")
+	sb.WriteString("." + prefix + "faint { opacity: 0.6; }\n")
+	sb.WriteString("." + prefix + "italic { font-style: italic; }\n")
+	sb.WriteString("." + prefix + "underline { text-decoration-line: underline; }\n")
+	sb.WriteString("." + prefix + "strike { text-decoration-line: line-through; }\n")
+	sb.WriteString("." + prefix + "underline." + prefix + "strike { text-decoration-line: underline line-through; }\n")
+	sb.WriteString("." + prefix + "blink { animation: " + prefix + "blink 1s steps(1) infinite; }\n")
+	sb.WriteString("@keyframes " + prefix + "blink { 50% { opacity: 0; } }\n")
+	for n := 0; n < 256; n++ {
+		c := p.index(uint8(n))
+		fg, _ := colorClass(prefix, "fg", IndexedColor(uint8(n)))
+		bg, _ := colorClass(prefix, "bg", IndexedColor(uint8(n)))
+		sb.WriteString("." + fg + " { color: " + c.Hex() + "; }\n")
+		sb.WriteString("." + bg + " { background-color: " + c.Hex() + "; }\n")
+	}
+	roles := make([]Style, 0, len(opts.Roles))
+	for s := range opts.Roles {
+		roles = append(roles, s)
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		return opts.Roles[roles[i]] < opts.Roles[roles[j]]
+	})
+	for _, s := range roles {
+		sb.WriteString("." + prefix + opts.Roles[s] + " { " + inlineCSS(p, s) + " }\n")
+	}
+	return sb.String()
+}
+
+// ToHTML converts colored text into HTML in one go
+func ToHTML(s string, opts *HTMLOptions) string {
+	var out bytes.Buffer
+	w := NewHTMLWriter(&out, opts)
+	_, _ = w.Write([]byte(s))
+	_ = w.Close()
+	return out.String()
+}
+
+func appendEscapedHTML(buf []byte, text []byte) []byte {
+	for _, c := range text {
+		switch c {
+		case '&':
+			buf = append(buf, "&amp;"...)
+		case '<':
+			buf = append(buf, "&lt;"...)
+		case '>':
+			buf = append(buf, "&gt;"...)
+		case '"':
+			buf = append(buf, "&#34;"...)
+		case '\'':
+			buf = append(buf, "&#39;"...)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
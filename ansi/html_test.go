@@ -0,0 +1,91 @@
+package ansi_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nerdwave-nick/rainbow/ansi"
+)
+
+const htmlInput = "\x1b[2;90m12:00\x1b[0m\x1b[31m|ERR \x1b[0m<a & 'b'>\n\t\x1b[1;38;2;255;135;0mkey\x1b[0m=\x1b[7mrev\x1b[0m"
+
+func TestAnsi_ToHTMLInline(t *testing.T) {
+	out := ansi.ToHTML(htmlInput, nil)
+	expected := `<pre style="color:#d0d0d0;background-color:#1c1c1c">` +
+		`<span style="color:#414141;">12:00</span>` +
+		`<span style="color:#cd3131;">|ERR </span>` +
+		`&lt;a &amp; &#39;b&#39;&gt;` + "\n\t" +
+		`<span style="color:#ff8700;font-weight:bold;">key</span>=` +
+		`<span style="color:#1c1c1c;background-color:#d0d0d0;">rev</span></pre>`
+	if out != expected {
+		t.Errorf("output \n%s did not match the expected output \n%s", out, expected)
+	}
+}
+
+func TestAnsi_ToHTMLClasses(t *testing.T) {
+	opts := &ansi.HTMLOptions{
+		Classes: true,
+		Roles: map[ansi.Style]string{
+			{Fg: ansi.BasicColor(1)}: "level-error",
+		},
+	}
+	out := ansi.ToHTML(htmlInput, opts)
+	expected := `<pre class="rb">` +
+		`<span class="rb-faint rb-fg-8">12:00</span>` +
+		`<span class="rb-level-error">|ERR </span>` +
+		`&lt;a &amp; &#39;b&#39;&gt;` + "\n\t" +
+		`<span class="rb-bold" style="color:#ff8700;">key</span>=` +
+		`<span style="color:#1c1c1c;background-color:#d0d0d0;">rev</span></pre>`
+	if out != expected {
+		t.Errorf("output \n%s did not match the expected output \n%s", out, expected)
+	}
+
+	css := ansi.Stylesheet(opts)
+	for _, rule := range []string{".rb-level-error { color:#cd3131; }", ".rb-fg-208 { color: #ff8700; }", ".rb-bg-232 { background-color: #080808; }"} {
+		if !strings.Contains(css, rule) {
+			t.Errorf("stylesheet did not contain %q", rule)
+		}
+	}
+}
+
+func TestAnsi_HTMLWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := ansi.NewHTMLWriter(&out, &ansi.HTMLOptions{Standalone: true, Title: "logs <1>"})
+	for i := range len(htmlInput) {
+		if _, err := w.Write([]byte(htmlInput[i : i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// spans are split where the writes were split, but read the same
+	whole := ansi.ToHTML(htmlInput, nil)
+	if !strings.HasPrefix(out.String(), "<!DOCTYPE html>") || !strings.Contains(out.String(), "<title>logs &lt;1&gt;</title>") {
+		t.Errorf("unexpected document \n%s", out.String())
+	}
+	if !strings.HasSuffix(out.String(), "</pre>\n</body>\n</html>\n") {
+		t.Errorf("document did not end properly \n%s", out.String())
+	}
+	if stripTags(out.String()) != stripTags(whole) {
+		t.Errorf("streamed text \n%q did not match \n%q", stripTags(out.String()), stripTags(whole))
+	}
+}
+
+func stripTags(s string) string {
+	var sb strings.Builder
+	inTag := false
+	body := s[strings.Index(s, "<pre"):strings.Index(s, "</pre>")]
+	for _, r := range body {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>':
+			inTag = false
+		case !inTag:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,128 @@
+package ansi
+
+import (
+	"strconv"
+)
+
+type RGB struct {
+	R, G, B uint8
+}
+
+// Hex is the color in CSS notation, like #ff8700
+func (c RGB) Hex() string {
+	const digits = "0123456789abcdef"
+	return string([]byte{
+		'#',
+		digits[c.R>>4], digits[c.R&15],
+		digits[c.G>>4], digits[c.G&15],
+		digits[c.B>>4], digits[c.B&15],
+	})
+}
+
+// Palette maps terminal colors to actual colors, the way
+// a terminal color scheme does
+type Palette struct {
+	Foreground RGB
+	Background RGB
+	// the 16 standard colors, 8-15 being the bright ones
+	Basic [16]RGB
+}
+
+// DefaultPalette is a dark palette close to the xterm defaults
+var DefaultPalette = Palette{
+	Foreground: RGB{0xd0, 0xd0, 0xd0},
+	Background: RGB{0x1c, 0x1c, 0x1c},
+	Basic: [16]RGB{
+		{0x00, 0x00, 0x00},
+		{0xcd, 0x31, 0x31},
+		{0x0d, 0xbc, 0x79},
+		{0xe5, 0xe5, 0x10},
+		{0x24, 0x72, 0xc8},
+		{0xbc, 0x3f, 0xbc},
+		{0x11, 0xa8, 0xcd},
+		{0xe5, 0xe5, 0xe5},
+		{0x66, 0x66, 0x66},
+		{0xf1, 0x4c, 0x4c},
+		{0x23, 0xd1, 0x8b},
+		{0xf5, 0xf5, 0x43},
+		{0x3b, 0x8e, 0xea},
+		{0xd6, 0x70, 0xd6},
+		{0x29, 0xb8, 0xdb},
+		{0xff, 0xff, 0xff},
+	},
+}
+
+// Resolve turns a terminal color into an actual one,
+// false for the zero Color
+func (p *Palette) Resolve(c Color) (RGB, bool) {
+	if r, g, b, ok := c.RGB(); ok {
+		return RGB{r, g, b}, true
+	}
+	n, ok := c.Indexed()
+	if !ok {
+		return RGB{}, false
+	}
+	return p.index(n), true
+}
+
+// index maps the 256 color palette: the 16 standard colors,
+// a 6x6x6 color cube and 24 shades of grey
+func (p *Palette) index(n uint8) RGB {
+	switch {
+	case n < 16:
+		return p.Basic[n]
+	case n < 232:
+		n -= 16
+		level := func(v uint8) uint8 {
+			if v == 0 {
+				return 0
+			}
+			return 55 + v*40
+		}
+		return RGB{level(n / 36), level(n / 6 % 6), level(n % 6)}
+	default:
+		grey := 8 + (n-232)*10
+		return RGB{grey, grey, grey}
+	}
+}
+
+// colors resolves the colors text in the given style is drawn with,
+// taking reverse video and faint text into account
+func (p *Palette) colors(s Style) (fg, bg RGB, bgSet bool) {
+	fg, ok := p.Resolve(s.Fg)
+	if !ok {
+		fg = p.Foreground
+	}
+	bg, bgSet = p.Resolve(s.Bg)
+	if s.Has(FlagReverse) {
+		if !bgSet {
+			bg = p.Background
+		}
+		fg, bg, bgSet = bg, fg, true
+	}
+	if s.Has(FlagFaint) {
+		back := p.Background
+		if bgSet {
+			back = bg
+		}
+		fg = mix(fg, back)
+	}
+	return fg, bg, bgSet
+}
+
+// mix halves the way from a to b, used for faint text
+func mix(a, b RGB) RGB {
+	return RGB{
+		uint8((int(a.R) + int(b.R)) / 2),
+		uint8((int(a.G) + int(b.G)) / 2),
+		uint8((int(a.B) + int(b.B)) / 2),
+	}
+}
+
+func colorClass(prefix, kind string, c Color) (string, bool) {
+	n, ok := c.Indexed()
+	if !ok {
+		return "", false
+	}
+	return prefix + kind + "-" + strconv.Itoa(int(n)), true
+}
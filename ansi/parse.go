@@ -0,0 +1,156 @@
+package ansi
+
+type parserState uint8
+
+const (
+	stateText parserState = iota
+	// right after an escape
+	stateEscape
+	// escape followed by intermediate bytes, waiting for the final byte
+	stateEscapeIntermediate
+	stateCSI
+	// OSC, DCS, SOS, PM and APC, ended by BEL or ST
+	stateString
+	// escape inside a string, possibly the start of ST
+	stateStringEscape
+)
+
+// longest escape sequence that is kept around, longer ones are
+// skipped without being interpreted
+const maxSequenceLen = 4096
+
+// Run is a piece of text and the style it is shown in
+type Run struct {
+	Style Style
+	// Text is only valid until the callback returns
+	Text []byte
+}
+
+// Parser splits a stream of bytes into runs of styled text.
+// SGR sequences change the style, every other escape sequence
+// (cursor movement, OSC, ...) is dropped. Sequences split across
+// calls to Feed are put back together. The zero value is ready to use.
+type Parser struct {
+	style Style
+	state parserState
+	seq   []byte
+	// the sequence grew too long and is skipped
+	overflow bool
+	// first byte after the escape, to tell the string sequences apart
+	intro byte
+}
+
+// Style is the style text would have at the current position
+func (p *Parser) Style() Style {
+	return p.style
+}
+
+// Reset forgets the current style and any unfinished sequence
+func (p *Parser) Reset() {
+	*p = Parser{seq: p.seq[:0]}
+}
+
+// Feed tokenizes b, calling emit for every run of text in it
+func (p *Parser) Feed(b []byte, emit func(Run)) {
+	start := 0
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch p.state {
+		case stateText:
+			if c == escape {
+				if i > start {
+					emit(Run{Style: p.style, Text: b[start:i]})
+				}
+				p.state = stateEscape
+				p.seq = p.seq[:0]
+				p.overflow = false
+			}
+		case stateEscape:
+			p.intro = c
+			switch {
+			case c == '[':
+				p.state = stateCSI
+			case c == ']' || c == 'P' || c == 'X' || c == '^' || c == '_':
+				p.state = stateString
+			case c >= 0x20 && c <= 0x2f:
+				p.state = stateEscapeIntermediate
+			case c == escape:
+				// stray escape, start over
+			default:
+				// two byte sequence like ESC 7
+				p.state = stateText
+				start = i + 1
+			}
+		case stateEscapeIntermediate:
+			if c >= 0x30 && c <= 0x7e {
+				p.state = stateText
+				start = i + 1
+			}
+		case stateCSI:
+			if c >= 0x40 && c <= 0x7e {
+				if c == 'm' && !p.overflow && isSGRParams(p.seq) {
+					p.style = p.style.ApplySGR(string(p.seq))
+				}
+				p.state = stateText
+				start = i + 1
+				continue
+			}
+			p.push(c)
+		case stateString:
+			switch c {
+			case '\a':
+				p.state = stateText
+				start = i + 1
+			case escape:
+				p.state = stateStringEscape
+			default:
+				p.push(c)
+			}
+		case stateStringEscape:
+			if c == '\\' {
+				p.state = stateText
+				start = i + 1
+				continue
+			}
+			p.push(escape)
+			p.push(c)
+			p.state = stateString
+		}
+	}
+	if p.state == stateText && start < len(b) {
+		emit(Run{Style: p.style, Text: b[start:]})
+	}
+}
+
+func (p *Parser) push(c byte) {
+	if len(p.seq) >= maxSequenceLen {
+		p.overflow = true
+		return
+	}
+	p.seq = append(p.seq, c)
+}
+
+// isSGRParams filters out private sequences like ESC[?25m
+func isSGRParams(params []byte) bool {
+	for _, c := range params {
+		if (c < '0' || c > '9') && c != ';' && c != ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// Runs splits s into runs of styled text, starting without a style.
+// Neighbouring runs of the same style are joined.
+func Runs(s string) []Run {
+	var p Parser
+	var runs []Run
+	p.Feed([]byte(s), func(r Run) {
+		if n := len(runs); n > 0 && runs[n-1].Style == r.Style {
+			runs[n-1].Text = append(runs[n-1].Text, r.Text...)
+			return
+		}
+		runs = append(runs, Run{Style: r.Style, Text: append([]byte(nil), r.Text...)})
+	})
+	return runs
+}
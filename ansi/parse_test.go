@@ -0,0 +1,91 @@
+package ansi_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nerdwave-nick/rainbow/ansi"
+)
+
+type run struct {
+	Style string
+	Text  string
+}
+
+func feedAll(p *ansi.Parser, chunks ...string) []run {
+	var runs []run
+	for _, c := range chunks {
+		p.Feed([]byte(c), func(r ansi.Run) {
+			if n := len(runs); n > 0 && runs[n-1].Style == r.Style.String() {
+				runs[n-1].Text += string(r.Text)
+				return
+			}
+			runs = append(runs, run{Style: r.Style.String(), Text: string(r.Text)})
+		})
+	}
+	return runs
+}
+
+func TestAnsi_Parser(t *testing.T) {
+	tests := []struct {
+		Input string
+		Runs  []run
+	}{
+		{
+			Input: "plain",
+			Runs:  []run{{Text: "plain"}},
+		},
+		{
+			Input: "\x1b[2;90m2024-01-01\x1b[0m\x1b[34m|INF \x1b[0mhello",
+			Runs: []run{
+				{Style: "\x1b[2;90m", Text: "2024-01-01"},
+				{Style: "\x1b[34m", Text: "|INF "},
+				{Text: "hello"},
+			},
+		},
+		{
+			Input: "a\x1b[1mb\x1b[31mc\x1b[22md\x1b[me",
+			Runs: []run{
+				{Text: "a"},
+				{Style: "\x1b[1m", Text: "b"},
+				{Style: "\x1b[1;31m", Text: "c"},
+				{Style: "\x1b[31m", Text: "d"},
+				{Text: "e"},
+			},
+		},
+		{
+			Input: "\x1b]8;;http://example.com\x1b\\link\x1b]8;;\alink\x1b[?25l\x1b[2K\x1b7!",
+			Runs:  []run{{Text: "linklink!"}},
+		},
+		{
+			Input: "\x1b[38;5;1mx\x1b(By",
+			Runs:  []run{{Style: "\x1b[38;5;1m", Text: "xy"}},
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("parser test %d", i), func(t *testing.T) {
+			whole := feedAll(&ansi.Parser{}, tt.Input)
+			if fmt.Sprint(whole) != fmt.Sprint(tt.Runs) {
+				t.Errorf("runs %q did not match the expected runs %q", whole, tt.Runs)
+			}
+
+			// byte by byte, every sequence gets split
+			chunks := make([]string, 0, len(tt.Input))
+			for j := range len(tt.Input) {
+				chunks = append(chunks, tt.Input[j:j+1])
+			}
+			split := feedAll(&ansi.Parser{}, chunks...)
+			if fmt.Sprint(split) != fmt.Sprint(tt.Runs) {
+				t.Errorf("split runs %q did not match the expected runs %q", split, tt.Runs)
+			}
+		})
+	}
+}
+
+func TestAnsi_Runs(t *testing.T) {
+	runs := ansi.Runs("\x1b[31mre\x1b[31md\x1b[0m plain")
+	if len(runs) != 2 || string(runs[0].Text) != "red" || string(runs[1].Text) != " plain" || !runs[1].Style.IsZero() {
+		t.Errorf("unexpected runs %q", runs)
+	}
+}
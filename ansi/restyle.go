@@ -0,0 +1,48 @@
+package ansi
+
+import "bytes"
+
+// Restyle copies src to dst with the style of every piece of text
+// passed through f, e.g. to put all of it on a background. Escape
+// sequences other than SGR are copied as they are.
+func Restyle(dst, src []byte, f func(Style) Style) []byte {
+	var style, written Style
+	current := f(style)
+	// styles are only written once there is something to show in them
+	flush := func() {
+		if current == written {
+			return
+		}
+		if !written.IsZero() {
+			dst = append(dst, "\x1b[0m"...)
+		}
+		dst = current.AppendSGR(dst)
+		written = current
+	}
+	for i := 0; i < len(src); {
+		if src[i] != escape {
+			n := bytes.IndexByte(src[i:], escape)
+			if n == -1 {
+				n = len(src) - i
+			}
+			flush()
+			dst = append(dst, src[i:i+n]...)
+			i += n
+			continue
+		}
+		n := SequenceLen(src[i:])
+		seq := src[i : i+n]
+		i += n
+		if params, ok := sgrParams(seq); ok {
+			style = style.ApplySGR(params)
+			current = f(style)
+			continue
+		}
+		flush()
+		dst = append(dst, seq...)
+	}
+	if !written.IsZero() {
+		dst = append(dst, "\x1b[0m"...)
+	}
+	return dst
+}
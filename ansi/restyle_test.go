@@ -0,0 +1,35 @@
+package ansi_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nerdwave-nick/rainbow/ansi"
+)
+
+func TestAnsi_Restyle(t *testing.T) {
+	onBlue := func(s ansi.Style) ansi.Style {
+		return s.WithBg(ansi.BasicColor(4))
+	}
+	tests := []struct {
+		Input  string
+		Output string
+	}{
+		{Input: "", Output: ""},
+		{Input: "plain", Output: "\x1b[44mplain\x1b[0m"},
+		{Input: "a\x1b[31mred\x1b[0mb", Output: "\x1b[44ma\x1b[0m\x1b[31;44mred\x1b[0m\x1b[44mb\x1b[0m"},
+		// styles without text in them are left out
+		{Input: "\x1b[1m\x1b[0m\x1b[32mx", Output: "\x1b[32;44mx\x1b[0m"},
+		// other sequences are kept
+		{Input: "\x1b]8;;https://example.com\x1b\\x\x1b]8;;\x1b\\", Output: "\x1b[44m\x1b]8;;https://example.com\x1b\\x\x1b]8;;\x1b\\\x1b[0m"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("restyle test %d", i), func(t *testing.T) {
+			actual := string(ansi.Restyle(nil, []byte(tt.Input), onBlue))
+			if actual != tt.Output {
+				t.Errorf("output %q did not match the expected output %q", actual, tt.Output)
+			}
+		})
+	}
+}
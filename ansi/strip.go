@@ -0,0 +1,43 @@
+package ansi
+
+import (
+	"io"
+)
+
+// StripWriter removes escape sequences from everything written to it
+// and passes the plain text on to the underlying writer.
+type StripWriter struct {
+	w      io.Writer
+	parser Parser
+	buf    []byte
+}
+
+func NewStripWriter(w io.Writer) *StripWriter {
+	return &StripWriter{w: w}
+}
+
+// Write always reports len(b) written unless the underlying writer fails,
+// even though fewer bytes reach it
+func (s *StripWriter) Write(b []byte) (int, error) {
+	s.buf = s.buf[:0]
+	s.parser.Feed(b, func(r Run) {
+		s.buf = append(s.buf, r.Text...)
+	})
+	if len(s.buf) == 0 {
+		return len(b), nil
+	}
+	if _, err := s.w.Write(s.buf); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Strip removes all escape sequences from s
+func Strip(s string) string {
+	var p Parser
+	out := make([]byte, 0, len(s))
+	p.Feed([]byte(s), func(r Run) {
+		out = append(out, r.Text...)
+	})
+	return string(out)
+}
@@ -0,0 +1,45 @@
+package ansi_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nerdwave-nick/rainbow/ansi"
+)
+
+func TestAnsi_StripWriter(t *testing.T) {
+	out := bytes.NewBuffer(make([]byte, 0))
+	w := ansi.NewStripWriter(out)
+	for _, chunk := range []string{"\x1b[3", "1mred\x1b", "[0m and ", "\x1b]0;title\x07plain\n"} {
+		n, err := w.Write([]byte(chunk))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != len(chunk) {
+			t.Errorf("wrote %d bytes instead of %d", n, len(chunk))
+		}
+	}
+	if out.String() != "red and plain\n" {
+		t.Errorf("output %q did not match the expected output %q", out.String(), "red and plain\n")
+	}
+}
+
+func TestAnsi_StripAndWidth(t *testing.T) {
+	tests := []struct {
+		Input string
+		Plain string
+		Width int
+	}{
+		{Input: "", Plain: "", Width: 0},
+		{Input: "\x1b[1;31mbold red\x1b[0m", Plain: "bold red", Width: 8},
+		{Input: "grün\x1b[0m", Plain: "grün", Width: 4},
+	}
+	for _, tt := range tests {
+		if plain := ansi.Strip(tt.Input); plain != tt.Plain {
+			t.Errorf("output %q did not match the expected output %q", plain, tt.Plain)
+		}
+		if width := ansi.Width(tt.Input); width != tt.Width {
+			t.Errorf("width %d of %q did not match the expected width %d", width, tt.Input, tt.Width)
+		}
+	}
+}
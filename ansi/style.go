@@ -0,0 +1,420 @@
+// Package ansi reads and writes text styled with ANSI escape sequences,
+// as written by rainbow.
+package ansi
+
+import (
+	"strconv"
+	"strings"
+)
+
+const escape = '\x1b'
+
+type colorKind uint8
+
+const (
+	colorNone colorKind = iota
+	// the 16 standard colors, 8-15 being the bright ones
+	colorBasic
+	// the xterm 256 color palette
+	colorIndexed
+	colorRGB
+)
+
+// Color is a terminal color, the zero value means no color
+type Color struct {
+	kind  colorKind
+	value uint32
+}
+
+// BasicColor is one of the 16 standard colors, 0-7 are
+// black, red, green, yellow, blue, magenta, cyan and white,
+// 8-15 their bright variants
+func BasicColor(n uint8) Color {
+	return Color{kind: colorBasic, value: uint32(n & 15)}
+}
+
+// IndexedColor is a color from the 256 color palette
+func IndexedColor(n uint8) Color {
+	return Color{kind: colorIndexed, value: uint32(n)}
+}
+
+func RGBColor(r, g, b uint8) Color {
+	return Color{kind: colorRGB, value: uint32(r)<<16 | uint32(g)<<8 | uint32(b)}
+}
+
+func (c Color) IsSet() bool {
+	return c.kind != colorNone
+}
+
+// Basic reports the color as one of the 16 standard colors
+func (c Color) Basic() (uint8, bool) {
+	return uint8(c.value), c.kind == colorBasic
+}
+
+// Indexed reports the color as a 256 color palette index,
+// standard colors map to their first 16 entries
+func (c Color) Indexed() (uint8, bool) {
+	return uint8(c.value), c.kind == colorBasic || c.kind == colorIndexed
+}
+
+func (c Color) RGB() (r, g, b uint8, ok bool) {
+	return uint8(c.value >> 16), uint8(c.value >> 8), uint8(c.value), c.kind == colorRGB
+}
+
+// appendSGR writes the parameters selecting the color, base is
+// 30 for foreground, 40 for background and 50 for underline colors
+func (c Color) appendSGR(buf []byte, base int) []byte {
+	switch c.kind {
+	case colorBasic:
+		// underline colors have no short form
+		if base == 50 {
+			buf = strconv.AppendInt(buf, 58, 10)
+			buf = append(buf, ";5;"...)
+			return strconv.AppendInt(buf, int64(c.value), 10)
+		}
+		if c.value < 8 {
+			return strconv.AppendInt(buf, int64(base)+int64(c.value), 10)
+		}
+		return strconv.AppendInt(buf, int64(base)+60+int64(c.value)-8, 10)
+	case colorIndexed:
+		buf = strconv.AppendInt(buf, int64(base)+8, 10)
+		buf = append(buf, ";5;"...)
+		return strconv.AppendInt(buf, int64(c.value), 10)
+	case colorRGB:
+		r, g, b, _ := c.RGB()
+		buf = strconv.AppendInt(buf, int64(base)+8, 10)
+		buf = append(buf, ";2;"...)
+		buf = strconv.AppendInt(buf, int64(r), 10)
+		buf = append(buf, ';')
+		buf = strconv.AppendInt(buf, int64(g), 10)
+		buf = append(buf, ';')
+		return strconv.AppendInt(buf, int64(b), 10)
+	}
+	return buf
+}
+
+type StyleFlags uint16
+
+const (
+	FlagBold StyleFlags = 1 << iota
+	FlagFaint
+	FlagItalic
+	FlagUnderline
+	FlagBlink
+	FlagReverse
+	FlagStrike
+)
+
+// sgr codes turning the flags on, in flag order
+var flagCodes = [...]int{1, 2, 3, 4, 5, 7, 9}
+
+// Style is a structured text style. It renders to an SGR escape
+// sequence only when written, and can be inspected and merged.
+// The zero value is no style at all.
+type Style struct {
+	Fg             Color
+	Bg             Color
+	UnderlineColor Color
+	Flags          StyleFlags
+
+	// whatever part of a parsed mod wasn't understood,
+	// written as it is in front of the SGR sequence
+	raw string
+}
+
+func (s Style) IsZero() bool {
+	return s == Style{}
+}
+
+func (s Style) WithFg(c Color) Style {
+	s.Fg = c
+	return s
+}
+
+func (s Style) WithBg(c Color) Style {
+	s.Bg = c
+	return s
+}
+
+func (s Style) WithUnderlineColor(c Color) Style {
+	s.UnderlineColor = c
+	return s
+}
+
+func (s Style) With(flags StyleFlags) Style {
+	s.Flags |= flags
+	return s
+}
+
+func (s Style) Without(flags StyleFlags) Style {
+	s.Flags &^= flags
+	return s
+}
+
+func (s Style) Has(flags StyleFlags) bool {
+	return s.Flags&flags == flags
+}
+
+// Merge layers o on top of s, colors set in o replace the ones in s
+// and the flags of both are combined.
+func (s Style) Merge(o Style) Style {
+	if o.Fg.IsSet() {
+		s.Fg = o.Fg
+	}
+	if o.Bg.IsSet() {
+		s.Bg = o.Bg
+	}
+	if o.UnderlineColor.IsSet() {
+		s.UnderlineColor = o.UnderlineColor
+	}
+	s.Flags |= o.Flags
+	s.raw += o.raw
+	return s
+}
+
+// Inherit fills in everything s leaves open from parent,
+// e.g. a key style extending the style of its group.
+func (s Style) Inherit(parent Style) Style {
+	return parent.Merge(s)
+}
+
+// AppendSGR writes the style as escape sequence, nothing for the zero style
+func (s Style) AppendSGR(buf []byte) []byte {
+	buf = append(buf, s.raw...)
+	if s.Flags == 0 && !s.Fg.IsSet() && !s.Bg.IsSet() && !s.UnderlineColor.IsSet() {
+		return buf
+	}
+	buf = append(buf, escape, '[')
+	first := true
+	sep := func() {
+		if !first {
+			buf = append(buf, ';')
+		}
+		first = false
+	}
+	for i, code := range flagCodes {
+		if s.Flags&(1<<i) != 0 {
+			sep()
+			buf = strconv.AppendInt(buf, int64(code), 10)
+		}
+	}
+	if s.Fg.IsSet() {
+		sep()
+		buf = s.Fg.appendSGR(buf, 30)
+	}
+	if s.Bg.IsSet() {
+		sep()
+		buf = s.Bg.appendSGR(buf, 40)
+	}
+	if s.UnderlineColor.IsSet() {
+		sep()
+		buf = s.UnderlineColor.appendSGR(buf, 50)
+	}
+	return append(buf, 'm')
+}
+
+// String is the style as escape sequence
+func (s Style) String() string {
+	return string(s.AppendSGR(nil))
+}
+
+// ParseStyle turns a string of SGR sequences into a Style.
+// Anything that isn't an SGR sequence is kept as it is and
+// written in front of the style, so every string survives the trip.
+func ParseStyle(seq string) Style {
+	var s Style
+	var raw strings.Builder
+	rest := seq
+	for len(rest) > 0 {
+		i := strings.IndexByte(rest, escape)
+		if i < 0 {
+			raw.WriteString(rest)
+			break
+		}
+		raw.WriteString(rest[:i])
+		rest = rest[i:]
+		params, n, ok := cutSGR(rest)
+		if !ok {
+			raw.WriteString(rest[:1])
+			rest = rest[1:]
+			continue
+		}
+		s = s.ApplySGR(params)
+		rest = rest[n:]
+	}
+	s.raw = raw.String()
+	return s
+}
+
+// cutSGR reads the parameters of an SGR sequence at the start of s
+// and how many bytes it's long
+func cutSGR(s string) (string, int, bool) {
+	if len(s) < 3 || s[0] != escape || s[1] != '[' {
+		return "", 0, false
+	}
+	for i := 2; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == 'm':
+			return s[2:i], i + 1, true
+		case (c >= '0' && c <= '9') || c == ';' || c == ':':
+		default:
+			return "", 0, false
+		}
+	}
+	return "", 0, false
+}
+
+// ApplySGR applies the parameters of an SGR sequence like "1;31"
+// the way a terminal would, including resets.
+func (s Style) ApplySGR(params string) Style {
+	codes := splitSGR(params)
+	for i := 0; i < len(codes); i++ {
+		code := codes[i].code
+		if sub := codes[i].sub; sub != nil {
+			if st, ok := s.applySubParams(code, sub); ok {
+				s = st
+				continue
+			}
+		}
+		switch {
+		case code == 0:
+			s = Style{raw: s.raw}
+		case code == 1:
+			s.Flags |= FlagBold
+		case code == 2:
+			s.Flags |= FlagFaint
+		case code == 3:
+			s.Flags |= FlagItalic
+		case code == 4:
+			s.Flags |= FlagUnderline
+		case code == 5 || code == 6:
+			s.Flags |= FlagBlink
+		case code == 7:
+			s.Flags |= FlagReverse
+		case code == 9:
+			s.Flags |= FlagStrike
+		case code == 22:
+			s.Flags &^= FlagBold | FlagFaint
+		case code == 23:
+			s.Flags &^= FlagItalic
+		case code == 24:
+			s.Flags &^= FlagUnderline
+		case code == 25:
+			s.Flags &^= FlagBlink
+		case code == 27:
+			s.Flags &^= FlagReverse
+		case code == 29:
+			s.Flags &^= FlagStrike
+		case code >= 30 && code <= 37:
+			s.Fg = BasicColor(uint8(code - 30))
+		case code == 39:
+			s.Fg = Color{}
+		case code >= 40 && code <= 47:
+			s.Bg = BasicColor(uint8(code - 40))
+		case code == 49:
+			s.Bg = Color{}
+		case code == 59:
+			s.UnderlineColor = Color{}
+		case code >= 90 && code <= 97:
+			s.Fg = BasicColor(uint8(code - 90 + 8))
+		case code >= 100 && code <= 107:
+			s.Bg = BasicColor(uint8(code - 100 + 8))
+		case code == 38 || code == 48 || code == 58:
+			c, n := extendedColor(codes[i+1:])
+			i += n
+			switch code {
+			case 38:
+				s.Fg = c
+			case 48:
+				s.Bg = c
+			case 58:
+				s.UnderlineColor = c
+			}
+		}
+	}
+	return s
+}
+
+// applySubParams applies a code with the sub-parameters after its
+// colons, like 4:3 for a curly underline or 38:2::r:g:b. Other codes
+// aren't known with sub-parameters, they are applied without them.
+func (s Style) applySubParams(code int, sub []int) (Style, bool) {
+	switch code {
+	case 4:
+		// the kind of underline, there is only one here
+		if sub[0] == 0 {
+			s.Flags &^= FlagUnderline
+		} else {
+			s.Flags |= FlagUnderline
+		}
+	case 38, 48, 58:
+		var c Color
+		switch {
+		case sub[0] == 5 && len(sub) >= 2:
+			c = IndexedColor(uint8(sub[1]))
+		case sub[0] == 2 && len(sub) >= 5:
+			// with the color space id in front
+			c = RGBColor(uint8(sub[2]), uint8(sub[3]), uint8(sub[4]))
+		case sub[0] == 2 && len(sub) == 4:
+			c = RGBColor(uint8(sub[1]), uint8(sub[2]), uint8(sub[3]))
+		default:
+			return s, true
+		}
+		switch code {
+		case 38:
+			s.Fg = c
+		case 48:
+			s.Bg = c
+		case 58:
+			s.UnderlineColor = c
+		}
+	default:
+		return s, false
+	}
+	return s, true
+}
+
+// extendedColor reads the 5;n or 2;r;g;b following a 38, 48 or 58
+func extendedColor(codes []sgrParam) (Color, int) {
+	if len(codes) >= 2 && codes[0].code == 5 {
+		return IndexedColor(uint8(codes[1].code)), 2
+	}
+	if len(codes) >= 4 && codes[0].code == 2 {
+		return RGBColor(uint8(codes[1].code), uint8(codes[2].code), uint8(codes[3].code)), 4
+	}
+	return Color{}, len(codes)
+}
+
+// sgrParam is a parameter of an SGR sequence, with the
+// sub-parameters after its colons if it has any
+type sgrParam struct {
+	code int
+	sub  []int
+}
+
+// splitSGR splits the parameters at the semicolons, empty ones are 0,
+// so "" and ";1" start with a reset like they do on a terminal
+func splitSGR(params string) []sgrParam {
+	codes := make([]sgrParam, 0, 4)
+	for _, p := range strings.Split(params, ";") {
+		code, sub, hasSub := strings.Cut(p, ":")
+		param := sgrParam{code: sgrNumber(code)}
+		if hasSub {
+			for _, n := range strings.Split(sub, ":") {
+				param.sub = append(param.sub, sgrNumber(n))
+			}
+		}
+		codes = append(codes, param)
+	}
+	return codes
+}
+
+// sgrNumber is the value of a parameter, 0 if it's empty
+func sgrNumber(p string) int {
+	n, err := strconv.Atoi(p)
+	if err != nil {
+		return 0
+	}
+	return n
+}
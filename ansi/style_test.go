@@ -0,0 +1,110 @@
+package ansi_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nerdwave-nick/rainbow/ansi"
+)
+
+func TestAnsi_ParseStyle(t *testing.T) {
+	tests := []struct {
+		Input  string
+		Style  ansi.Style
+		Output string
+	}{
+		{
+			Input:  "",
+			Style:  ansi.Style{},
+			Output: "",
+		},
+		{
+			Input:  "\x1b[31;1;106m",
+			Style:  ansi.Style{Fg: ansi.BasicColor(1), Bg: ansi.BasicColor(14), Flags: ansi.FlagBold},
+			Output: "\x1b[1;31;106m",
+		},
+		{
+			Input:  "\x1b[2;97;3;2m",
+			Style:  ansi.Style{Fg: ansi.BasicColor(15), Flags: ansi.FlagFaint | ansi.FlagItalic},
+			Output: "\x1b[2;3;97m",
+		},
+		{
+			Input:  "\x1b[38;5;208m\x1b[48;2;1;2;3;4;58;5;1m",
+			Style:  ansi.Style{Fg: ansi.IndexedColor(208), Bg: ansi.RGBColor(1, 2, 3), UnderlineColor: ansi.IndexedColor(1), Flags: ansi.FlagUnderline},
+			Output: "\x1b[4;38;5;208;48;2;1;2;3;58;5;1m",
+		},
+		{
+			Input:  "\x1b[1;31m\x1b[0;4m\x1b[24;7;39m",
+			Style:  ansi.Style{Flags: ansi.FlagReverse},
+			Output: "\x1b[7m",
+		},
+		{
+			// an empty parameter is a reset
+			Input:  "\x1b[1;31m\x1b[;3m",
+			Style:  ansi.Style{Flags: ansi.FlagItalic},
+			Output: "\x1b[3m",
+		},
+		{
+			Input:  "\x1b[31m\x1b[m",
+			Output: "",
+		},
+		{
+			// a curly underline, not an underline and italic
+			Input:  "\x1b[4:3m",
+			Style:  ansi.Style{Flags: ansi.FlagUnderline},
+			Output: "\x1b[4m",
+		},
+		{
+			Input:  "\x1b[4;4:0m",
+			Output: "",
+		},
+		{
+			Input:  "\x1b[38:2::1:2:3;48:5:208;58:2:4:5:6;1:2m",
+			Style:  ansi.Style{Fg: ansi.RGBColor(1, 2, 3), Bg: ansi.IndexedColor(208), UnderlineColor: ansi.RGBColor(4, 5, 6), Flags: ansi.FlagBold},
+			Output: "\x1b[1;38;2;1;2;3;48;5;208;58;2;4;5;6m",
+		},
+		{
+			Input:  "<le>",
+			Output: "<le>",
+		},
+		{
+			Input:  "<x>\x1b[3m\x1b[?25l",
+			Output: "<x>\x1b[?25l\x1b[3m",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("parse style test %d", i), func(t *testing.T) {
+			st := ansi.ParseStyle(tt.Input)
+			if st.String() != tt.Output {
+				t.Errorf("output %q did not match the expected output %q", st.String(), tt.Output)
+			}
+			if !tt.Style.IsZero() && st != tt.Style {
+				t.Errorf("style %+v did not match the expected style %+v", st, tt.Style)
+			}
+		})
+	}
+}
+
+func TestAnsi_StyleMerge(t *testing.T) {
+	group := ansi.Style{Fg: ansi.BasicColor(4), Flags: ansi.FlagFaint}
+	key := ansi.Style{Flags: ansi.FlagItalic}
+	expected := ansi.Style{Fg: ansi.BasicColor(4), Flags: ansi.FlagFaint | ansi.FlagItalic}
+	if merged := key.Inherit(group); merged != expected {
+		t.Errorf("style %+v did not match the expected style %+v", merged, expected)
+	}
+
+	over := ansi.Style{Fg: ansi.RGBColor(255, 0, 0), Bg: ansi.BasicColor(0)}
+	expected = ansi.Style{Fg: ansi.RGBColor(255, 0, 0), Bg: ansi.BasicColor(0), Flags: ansi.FlagFaint}
+	if merged := group.Merge(over); merged != expected {
+		t.Errorf("style %+v did not match the expected style %+v", merged, expected)
+	}
+
+	bold := group.With(ansi.FlagBold | ansi.FlagStrike).Without(ansi.FlagFaint)
+	if !bold.Has(ansi.FlagBold|ansi.FlagStrike) || bold.Has(ansi.FlagFaint) {
+		t.Errorf("unexpected flags %b", bold.Flags)
+	}
+	if bold.String() != "\x1b[1;9;34m" {
+		t.Errorf("output %q did not match the expected output %q", bold.String(), "\x1b[1;9;34m")
+	}
+}
@@ -0,0 +1,207 @@
+package ansi
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type SVGOptions struct {
+	// Palette the 16 and 256 colors are shown in, DefaultPalette if nil
+	Palette *Palette
+	// FontFamily defaults to a list of common monospace fonts
+	FontFamily string
+	// FontSize in pixels, defaults to 14
+	FontSize float64
+	// LineHeight as a multiple of the font size, defaults to 1.4
+	LineHeight float64
+	// Columns is the width of the grid, defaults to the longest line
+	Columns int
+	// Chrome draws a window frame with a title bar around the text
+	Chrome bool
+	Title  string
+}
+
+const (
+	svgDefaultFont = "ui-monospace, SFMono-Regular, Menlo, Consolas, 'DejaVu Sans Mono', monospace"
+	// width of a monospace cell relative to the font size,
+	// close enough for the usual monospace fonts
+	svgCellRatio = 0.6
+	svgPadding   = 16.0
+	svgTitleBar  = 32.0
+)
+
+type svgSpan struct {
+	col   int
+	cols  int
+	style Style
+	text  string
+}
+
+// RenderSVG draws colored text into an SVG image of a terminal,
+// e.g. to keep documentation screenshots up to date from go generate.
+func RenderSVG(w io.Writer, text string, opts *SVGOptions) error {
+	if opts == nil {
+		opts = &SVGOptions{}
+	}
+	p := opts.Palette
+	if p == nil {
+		p = &DefaultPalette
+	}
+	fontFamily := opts.FontFamily
+	if fontFamily == "" {
+		fontFamily = svgDefaultFont
+	}
+	fontSize := opts.FontSize
+	if fontSize <= 0 {
+		fontSize = 14
+	}
+	lineHeight := opts.LineHeight
+	if lineHeight <= 0 {
+		lineHeight = 1.4
+	}
+	cellW := fontSize * svgCellRatio
+	cellH := fontSize * lineHeight
+
+	lines := svgLines(strings.TrimSuffix(text, "\n"))
+	cols := opts.Columns
+	if cols <= 0 {
+		for _, l := range lines {
+			if n := len(l); n > 0 && l[n-1].col+l[n-1].cols > cols {
+				cols = l[n-1].col + l[n-1].cols
+			}
+		}
+	}
+
+	top := svgPadding
+	if opts.Chrome {
+		top += svgTitleBar
+	}
+	width := svgPadding*2 + float64(cols)*cellW
+	height := top + svgPadding + float64(len(lines))*cellH
+
+	var b bytes.Buffer
+	b.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" width="` + px(width) + `" height="` + px(height) + `" viewBox="0 0 ` + px(width) + ` ` + px(height) + `">` + "\n")
+	if opts.Chrome {
+		b.WriteString(`<rect width="` + px(width) + `" height="` + px(height) + `" rx="8" fill="` + p.Background.Hex() + `"/>` + "\n")
+		for i, c := range []string{"#ff5f56", "#ffbd2e", "#27c93f"} {
+			b.WriteString(`<circle cx="` + px(20+float64(i)*20) + `" cy="` + px(svgTitleBar/2+4) + `" r="6" fill="` + c + `"/>` + "\n")
+		}
+		if opts.Title != "" {
+			b.WriteString(`<text x="` + px(width/2) + `" y="` + px(svgTitleBar/2+8) + `" text-anchor="middle" font-family="` + escapeXML(fontFamily) + `" font-size="` + px(fontSize*0.9) + `" fill="` + mix(p.Foreground, p.Background).Hex() + `">` + escapeXML(opts.Title) + `</text>` + "\n")
+		}
+	} else {
+		b.WriteString(`<rect width="` + px(width) + `" height="` + px(height) + `" fill="` + p.Background.Hex() + `"/>` + "\n")
+	}
+
+	// backgrounds first, so no text is drawn over
+	for i, l := range lines {
+		y := top + float64(i)*cellH
+		for _, s := range l {
+			if _, bg, ok := p.colors(s.style); ok {
+				b.WriteString(`<rect x="` + px(svgPadding+float64(s.col)*cellW) + `" y="` + px(y) + `" width="` + px(float64(s.cols)*cellW) + `" height="` + px(cellH) + `" fill="` + bg.Hex() + `"/>` + "\n")
+			}
+		}
+	}
+
+	b.WriteString(`<g font-family="` + escapeXML(fontFamily) + `" font-size="` + px(fontSize) + `" fill="` + p.Foreground.Hex() + `" xml:space="preserve">` + "\n")
+	for i, l := range lines {
+		if len(l) == 0 {
+			continue
+		}
+		// baseline sits a bit above the bottom of the cell
+		y := top + float64(i)*cellH + (cellH+fontSize)/2 - fontSize*0.15
+		b.WriteString(`<text y="` + px(y) + `">`)
+		for _, s := range l {
+			fg, _, _ := p.colors(s.style)
+			b.WriteString(`<tspan x="` + px(svgPadding+float64(s.col)*cellW) + `"`)
+			if s.style.Fg.IsSet() || s.style.Has(FlagReverse) || s.style.Has(FlagFaint) {
+				b.WriteString(` fill="` + fg.Hex() + `"`)
+			}
+			if s.style.Has(FlagBold) {
+				b.WriteString(` font-weight="bold"`)
+			}
+			if s.style.Has(FlagItalic) {
+				b.WriteString(` font-style="italic"`)
+			}
+			decorations := make([]string, 0, 2)
+			if s.style.Has(FlagUnderline) {
+				decorations = append(decorations, "underline")
+			}
+			if s.style.Has(FlagStrike) {
+				decorations = append(decorations, "line-through")
+			}
+			if len(decorations) > 0 {
+				b.WriteString(` text-decoration="` + strings.Join(decorations, " ") + `"`)
+			}
+			b.WriteString(`>` + escapeXML(s.text) + `</tspan>`)
+		}
+		b.WriteString("</text>\n")
+	}
+	b.WriteString("</g>\n</svg>\n")
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// ToSVG is RenderSVG into a string
+func ToSVG(text string, opts *SVGOptions) string {
+	var b bytes.Buffer
+	_ = RenderSVG(&b, text, opts)
+	return b.String()
+}
+
+// svgLines lays out the runs of text on the cell grid, line by line,
+// expanding tabs and dropping other control characters
+func svgLines(text string) [][]svgSpan {
+	lines := [][]svgSpan{nil}
+	col := 0
+	var p Parser
+	p.Feed([]byte(text), func(r Run) {
+		rest := r.Text
+		for len(rest) > 0 {
+			var sb strings.Builder
+			start := col
+			for len(rest) > 0 {
+				c, _ := utf8.DecodeRune(rest)
+				if c == '\n' {
+					break
+				}
+				size, width := NextCluster(rest)
+				switch {
+				case c == '\t':
+					next := (col/TabWidth + 1) * TabWidth
+					sb.WriteString(strings.Repeat(" ", next-col))
+					col = next
+				case c < 0x20 || c == 0x7f:
+				default:
+					sb.Write(rest[:size])
+					col += width
+				}
+				rest = rest[size:]
+			}
+			if col > start {
+				last := len(lines) - 1
+				lines[last] = append(lines[last], svgSpan{col: start, cols: col - start, style: r.Style, text: sb.String()})
+			}
+			if len(rest) > 0 {
+				// a newline
+				rest = rest[1:]
+				lines = append(lines, nil)
+				col = 0
+			}
+		}
+	})
+	return lines
+}
+
+func px(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
+
+func escapeXML(s string) string {
+	return string(appendEscapedHTML(nil, []byte(s)))
+}
@@ -0,0 +1,41 @@
+package ansi_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nerdwave-nick/rainbow/ansi"
+)
+
+func TestAnsi_ToSVG(t *testing.T) {
+	out := ansi.ToSVG("\x1b[31m|ERR \x1b[0m<msg>\n\tk=\x1b[1;38;5;208mv\x1b[0m\x1b[44m \x1b[0m\n", nil)
+	expected := []string{
+		// 12 columns at 8.4px plus padding, 2 lines of 19.6px plus padding
+		`<svg xmlns="http://www.w3.org/2000/svg" width="132.8" height="71.2" viewBox="0 0 132.8 71.2">`,
+		`<rect width="132.8" height="71.2" fill="#1c1c1c"/>`,
+		// the blue background behind the space at column 12
+		`<rect x="108.4" y="35.6" width="8.4" height="19.6" fill="#2472c8"/>`,
+		`<text y="30.7"><tspan x="16" fill="#cd3131">|ERR </tspan><tspan x="58">&lt;msg&gt;</tspan></text>`,
+		`<text y="50.3"><tspan x="16">        k=</tspan><tspan x="100" fill="#ff8700" font-weight="bold">v</tspan><tspan x="108.4"> </tspan></text>`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Errorf("svg \n%s did not contain \n%s", out, e)
+		}
+	}
+}
+
+func TestAnsi_ToSVGChrome(t *testing.T) {
+	out := ansi.ToSVG("hi", &ansi.SVGOptions{Chrome: true, Title: "a & b", FontSize: 10, Columns: 20})
+	expected := []string{
+		`width="152" height="78"`,
+		`rx="8"`,
+		`<circle cx="20" cy="20" r="6" fill="#ff5f56"/>`,
+		`>a &amp; b</text>`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Errorf("svg \n%s did not contain \n%s", out, e)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package ansi
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+//go:generate go run gen_tables.go
+
+// TabWidth is the distance between tab stops
+const TabWidth = 8
+
+const (
+	zeroWidthJoiner   = 0x200d
+	textPresentation  = 0xfe0e
+	emojiPresentation = 0xfe0f
+)
+
+// RuneWidth is the number of columns a rune takes up on screen,
+// 0 for control characters and combining marks, 2 for wide ones.
+// Runes that are wide in East Asian text only count as narrow.
+func RuneWidth(r rune) int {
+	switch {
+	case r < 0x20 || (r >= 0x7f && r < 0xa0):
+		return 0
+	case r < 0x300:
+		// nothing below is wide or zero width. The soft hyphen
+		// counts as 1 too on purpose, terminals draw it as a hyphen
+		return 1
+	case inTable(r, zeroTable):
+		return 0
+	case inTable(r, wideTable):
+		return 2
+	}
+	return 1
+}
+
+func inTable(r rune, table [][2]rune) bool {
+	i := sort.Search(len(table), func(i int) bool { return table[i][1] >= r })
+	return i < len(table) && table[i][0] <= r
+}
+
+// NextCluster finds the character b starts with, the way a terminal
+// puts it on screen, and returns its length in bytes and its width.
+// A character is a rune along with the combining marks, variation
+// selectors and skin tones after it, the runes joined to it with
+// zero width joiners, or a pair of regional indicators making a flag.
+// Emoji presentation selectors widen the character to two columns.
+func NextCluster(b []byte) (size, width int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	first, size := utf8.DecodeRune(b)
+	width = RuneWidth(first)
+	if first < 0x20 || first == 0x7f {
+		// control characters are always on their own
+		return size, width
+	}
+	flag := isRegionalIndicator(first)
+	for size < len(b) {
+		r, n := utf8.DecodeRune(b[size:])
+		switch {
+		case r == zeroWidthJoiner:
+			size += n
+			// the next rune is drawn as part of this one
+			if size < len(b) {
+				if next, m := utf8.DecodeRune(b[size:]); next >= 0x20 && next != 0x7f {
+					size += m
+				}
+			}
+			continue
+		case r == emojiPresentation:
+			width = max(width, 2)
+		case r == textPresentation, isEmojiModifier(r):
+		case flag && isRegionalIndicator(r):
+			flag = false
+			width = 2
+		case r >= 0x20 && RuneWidth(r) == 0 && !(r >= 0x7f && r < 0xa0):
+			// combining marks and the like
+		default:
+			return size, width
+		}
+		flag = false
+		size += n
+	}
+	return size, width
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+// isEmojiModifier are the skin tones
+func isEmojiModifier(r rune) bool {
+	return r >= 0x1f3fb && r <= 0x1f3ff
+}
+
+// Width is the number of columns s takes up on screen, ignoring
+// escape sequences. Tabs go to the next tab stop, counted from
+// the start of s.
+func Width(s string) int {
+	var p Parser
+	width := 0
+	p.Feed([]byte(s), func(r Run) {
+		width = textColumn(width, r.Text)
+	})
+	return width
+}
+
+// TextWidth is the number of columns b takes up on screen,
+// b must not contain escape sequences, see Width
+func TextWidth(b []byte) int {
+	return textColumn(0, b)
+}
+
+// textColumn is the column after writing b from col on
+func textColumn(col int, b []byte) int {
+	for len(b) > 0 {
+		n, w := NextCluster(b)
+		if b[0] == '\t' {
+			w = (col/TabWidth+1)*TabWidth - col
+		}
+		col += w
+		b = b[n:]
+	}
+	return col
+}
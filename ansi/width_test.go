@@ -0,0 +1,72 @@
+package ansi_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nerdwave-nick/rainbow/ansi"
+)
+
+func TestAnsi_Width(t *testing.T) {
+	tests := []struct {
+		Input string
+		Width int
+	}{
+		{Input: "plain", Width: 5},
+		{Input: "\x1b[1;31mred\x1b[0m", Width: 3},
+		{Input: "漢字", Width: 4},
+		{Input: "ｆｕｌｌ", Width: 8},
+		{Input: "한국어", Width: 6},
+		// the jamo make up a single syllable
+		{Input: "\u1100\u1161\u11a8", Width: 2},
+		{Input: "e\u0301te\u0301", Width: 3},
+		{Input: "a\u200bb", Width: 2},
+		{Input: "\u00ad", Width: 1},
+		{Input: "tab\there", Width: 12},
+		{Input: "\x1b[1m\t\x1b[0m\tx", Width: 17},
+		{Input: "🌈", Width: 2},
+		{Input: "❤", Width: 1},
+		{Input: "\u2764\ufe0f", Width: 2},
+		{Input: "👍🏽", Width: 2},
+		{Input: "\U0001f469\u200d\U0001f469\u200d\U0001f467", Width: 2},
+		{Input: "\U0001f3f3\ufe0f\u200d\U0001f308", Width: 2},
+		{Input: "🇩🇪🇯🇵", Width: 4},
+		{Input: "\u00e9", Width: 1},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("width test %d", i), func(t *testing.T) {
+			actual := ansi.Width(tt.Input)
+			if actual != tt.Width {
+				t.Errorf("width %d of %q did not match the expected width %d", actual, tt.Input, tt.Width)
+			}
+		})
+	}
+}
+
+func TestAnsi_NextCluster(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Clusters []string
+	}{
+		{Input: "ab", Clusters: []string{"a", "b"}},
+		{Input: "e\u0301x", Clusters: []string{"e\u0301", "x"}},
+		{Input: "\U0001f469\u200d\U0001f467!", Clusters: []string{"\U0001f469\u200d\U0001f467", "!"}},
+		{Input: "🇩🇪🇯", Clusters: []string{"🇩🇪", "🇯"}},
+		{Input: "a\n\u0301", Clusters: []string{"a", "\n", "\u0301"}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("cluster test %d", i), func(t *testing.T) {
+			var clusters []string
+			for b := []byte(tt.Input); len(b) > 0; {
+				n, _ := ansi.NextCluster(b)
+				clusters = append(clusters, string(b[:n]))
+				b = b[n:]
+			}
+			if fmt.Sprint(clusters) != fmt.Sprint(tt.Clusters) {
+				t.Errorf("output %q did not match the expected output %q", clusters, tt.Clusters)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package ansi
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+// Wrap breaks every line of s into lines at most width columns wide,
+// see AppendWrapped
+func Wrap(s string, width, indent int) string {
+	var out []byte
+	for i, line := range strings.Split(s, "\n") {
+		if i > 0 {
+			out = append(out, '\n')
+		}
+		out = AppendWrapped(out, []byte(line), width, indent)
+	}
+	return string(out)
+}
+
+// AppendWrapped appends a single line to dst, broken into lines at most
+// width columns wide. It breaks after spaces where it can and in the
+// middle of words where it has to, never inside of a character, see
+// NextCluster, or an escape sequence. Lines after the first are indented by indent
+// columns, at most half the width. The style and an open OSC 8
+// hyperlink are closed at the end of every broken line and opened
+// again after the indent.
+func AppendWrapped(dst, line []byte, width, indent int) []byte {
+	if width <= 0 {
+		return append(dst, line...)
+	}
+	indent = max(min(indent, width/2), 0)
+
+	var style Style
+	// the OSC 8 sequence of the open hyperlink, nil outside of one
+	var link []byte
+	col := 0
+	// something other than blanks is on the current line
+	content := false
+	// offset in dst right after the last blank, and the style there
+	brk := -1
+	var brkStyle Style
+	var brkLink []byte
+
+	for i := 0; i < len(line); {
+		if line[i] == escape {
+			n := SequenceLen(line[i:])
+			seq := line[i : i+n]
+			if params, ok := sgrParams(seq); ok {
+				style = style.ApplySGR(params)
+			} else if open, ok := isLink(seq); ok {
+				link = nil
+				if open {
+					link = seq
+				}
+			}
+			dst = append(dst, seq...)
+			i += n
+			continue
+		}
+		r, _ := utf8.DecodeRune(line[i:])
+		size, w := NextCluster(line[i:])
+		if r == '\t' {
+			w = (col/TabWidth+1)*TabWidth - col
+		}
+		blank := r == ' ' || r == '\t'
+
+		if !blank && w > 0 && content && col+w > width {
+			if brk != -1 {
+				tail := append([]byte(nil), dst[brk:]...)
+				dst = appendBreak(trimBlanks(dst[:brk]), brkStyle, brkLink, indent)
+				dst = append(dst, tail...)
+				col = advance(indent, tail)
+				content = col > indent
+			} else {
+				dst = appendBreak(dst, style, link, indent)
+				col = indent
+				content = false
+			}
+			brk = -1
+			// try the same rune again on the new line
+			continue
+		}
+
+		dst = append(dst, line[i:i+size]...)
+		col += w
+		i += size
+		if blank && content {
+			brk = len(dst)
+			brkStyle = style
+			brkLink = link
+		} else if !blank && w > 0 {
+			content = true
+		}
+	}
+	return dst
+}
+
+func appendBreak(dst []byte, style Style, link []byte, indent int) []byte {
+	if link != nil {
+		dst = append(dst, "\x1b]8;;\x1b\\"...)
+	}
+	if !style.IsZero() {
+		dst = append(dst, "\x1b[0m"...)
+	}
+	dst = append(dst, '\n')
+	for range indent {
+		dst = append(dst, ' ')
+	}
+	dst = style.AppendSGR(dst)
+	return append(dst, link...)
+}
+
+// isLink tells if seq is an OSC 8 hyperlink sequence,
+// and if it opens a link rather than closing one
+func isLink(seq []byte) (open, ok bool) {
+	if !bytes.HasPrefix(seq, []byte("\x1b]8;")) {
+		return false, false
+	}
+	// params, then the uri up to the terminator
+	rest := seq[len("\x1b]8;"):]
+	semi := bytes.IndexByte(rest, ';')
+	if semi == -1 {
+		return false, false
+	}
+	uri := bytes.TrimSuffix(bytes.TrimSuffix(rest[semi+1:], []byte("\x1b\\")), []byte("\a"))
+	return len(uri) > 0, true
+}
+
+// trimBlanks drops the blanks at the end of a line that is broken there
+func trimBlanks(b []byte) []byte {
+	for len(b) > 0 && (b[len(b)-1] == ' ' || b[len(b)-1] == '\t') {
+		b = b[:len(b)-1]
+	}
+	return b
+}
+
+// advance is the column after writing b from col on
+func advance(col int, b []byte) int {
+	for i := 0; i < len(b); {
+		if b[i] == escape {
+			i += SequenceLen(b[i:])
+			continue
+		}
+		size, w := NextCluster(b[i:])
+		if b[i] == '\t' {
+			w = (col/TabWidth+1)*TabWidth - col
+		}
+		col += w
+		i += size
+	}
+	return col
+}
+
+// SequenceLen is the length of the escape sequence b starts with,
+// all of b if it isn't finished. CSI, OSC, DCS and the other string
+// sequences, and the ones with intermediate bytes are known.
+func SequenceLen[T string | []byte](b T) int {
+	if len(b) < 2 {
+		return len(b)
+	}
+	switch c := b[1]; {
+	case c == '[':
+		for i := 2; i < len(b); i++ {
+			if b[i] >= 0x40 && b[i] <= 0x7e {
+				return i + 1
+			}
+		}
+	case c == ']' || c == 'P' || c == 'X' || c == '^' || c == '_':
+		for i := 2; i < len(b); i++ {
+			if b[i] == '\a' {
+				return i + 1
+			}
+			if b[i] == escape && i+1 < len(b) && b[i+1] == '\\' {
+				return i + 2
+			}
+		}
+	case c >= 0x20 && c <= 0x2f:
+		for i := 2; i < len(b); i++ {
+			if b[i] >= 0x30 && b[i] <= 0x7e {
+				return i + 1
+			}
+		}
+	default:
+		return 2
+	}
+	return len(b)
+}
+
+// sgrParams are the parameters of a complete SGR sequence
+func sgrParams(seq []byte) (string, bool) {
+	if len(seq) < 3 || seq[1] != '[' || seq[len(seq)-1] != 'm' {
+		return "", false
+	}
+	params := seq[2 : len(seq)-1]
+	if !isSGRParams(params) {
+		return "", false
+	}
+	return string(params), true
+}
@@ -0,0 +1,81 @@
+package ansi_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nerdwave-nick/rainbow/ansi"
+)
+
+func TestAnsi_Wrap(t *testing.T) {
+	tests := []struct {
+		Input  string
+		Width  int
+		Indent int
+		Output string
+	}{
+		{
+			Input:  "the quick brown fox jumps",
+			Width:  10,
+			Output: "the quick\nbrown fox\njumps",
+		},
+		{
+			Input:  "key=the quick brown fox",
+			Width:  12,
+			Indent: 4,
+			Output: "key=the\n    quick\n    brown\n    fox",
+		},
+		{
+			// no spaces, broken where it has to
+			Input:  "abcdefghijklmnop",
+			Width:  6,
+			Indent: 2,
+			Output: "abcdef\n  ghij\n  klmn\n  op",
+		},
+		{
+			// wide characters stay whole
+			Input:  "漢字漢字漢",
+			Width:  5,
+			Output: "漢字\n漢字\n漢",
+		},
+		{
+			// the style is closed and opened again around the break
+			Input:  "\x1b[31mred text\x1b[0m and more",
+			Width:  4,
+			Output: "\x1b[31mred\x1b[0m\n\x1b[31mtext\x1b[0m\nand\nmore",
+		},
+		{
+			// escape sequences are never split and take no room
+			Input:  "ab\x1b]8;;https://example.com\x1b\\cd\x1b]8;;\x1b\\ef",
+			Width:  4,
+			Output: "ab\x1b]8;;https://example.com\x1b\\cd\x1b]8;;\x1b\\\nef",
+		},
+		{
+			// a link is closed before the break and opened again after the indent
+			Input:  "\x1b[4m\x1b]8;;https://example.com\x1b\\see the docs\x1b]8;;\x1b\\\x1b[0m ok",
+			Width:  8,
+			Indent: 2,
+			Output: "\x1b[4m\x1b]8;;https://example.com\x1b\\see the\x1b]8;;\x1b\\\x1b[0m\n  \x1b[4m\x1b]8;;https://example.com\x1b\\docs\x1b]8;;\x1b\\\x1b[0m\n  ok",
+		},
+		{
+			Input:  "\tk=v w",
+			Width:  11,
+			Indent: 10,
+			Output: "\tk=v\n     w",
+		},
+		{
+			Input:  "a\nb c",
+			Width:  2,
+			Output: "a\nb\nc",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("wrap test %d", i), func(t *testing.T) {
+			actual := ansi.Wrap(tt.Input, tt.Width, tt.Indent)
+			if actual != tt.Output {
+				t.Errorf("output %q did not match the expected output %q", actual, tt.Output)
+			}
+		})
+	}
+}
@@ -35,3 +35,29 @@ func TestRainbow_NewAnsiMods(t *testing.T) {
 		})
 	}
 }
+
+func TestRainbow_ParseMod(t *testing.T) {
+	tests := []struct {
+		Input  string
+		Output rainbow.AnsiMod
+		Err    bool
+	}{
+		{Input: "", Output: ""},
+		{Input: "red", Output: "\x1b[31m"},
+		{Input: "Bold hi-cyan, bg_black", Output: "\x1b[1;96;40m"},
+		{Input: "\x1b[4m", Output: "\x1b[4m"},
+		{Input: "purple", Err: true},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("parse mod test %d", i), func(t *testing.T) {
+			retVal, err := rainbow.ParseMod(tt.Input)
+			if (err != nil) != tt.Err {
+				t.Fatalf("unexpected error state %v", err)
+			}
+			if retVal != tt.Output {
+				t.Errorf("output %q did not match the expected output %q", retVal, tt.Output)
+			}
+		})
+	}
+}
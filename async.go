@@ -0,0 +1,353 @@
+package rainbow
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+// ErrClosed is returned when logging to an AsyncHandler after Close
+var ErrClosed = errors.New("rainbow: handler is closed")
+
+type DropPolicy int
+
+const (
+	// DropPolicyBlock waits for room in the queue, slowing down the
+	// logging goroutines to the speed of the writer
+	DropPolicyBlock DropPolicy = iota
+	// DropPolicyNewest drops the record being logged
+	DropPolicyNewest
+	// DropPolicyOldest drops the record that has been waiting the longest
+	DropPolicyOldest
+	// DropPolicyBelowLevel drops records below AsyncOptions.Level,
+	// and waits for room for all others
+	DropPolicyBelowLevel
+)
+
+const defaultQueueSize = 1024
+
+type AsyncOptions struct {
+	// QueueSize is the number of records waiting to be written,
+	// defaults to 1024
+	QueueSize int
+	// Policy decides what happens to records while the queue is full
+	Policy DropPolicy
+	// Level for DropPolicyBelowLevel
+	Level slog.Level
+}
+
+// AsyncHandler writes records from a goroutine of its own, so
+// a slow writer doesn't hold up the goroutines that log.
+// Records are formatted right away, on the logging goroutine,
+// and wait in a queue to be written. Handlers from WithAttrs
+// and WithGroup share the queue. Close it when done.
+type AsyncHandler struct {
+	handler slog.Handler
+	// set when handler can format without writing
+	text  *TextHandler
+	queue *asyncQueue
+}
+
+// Async wraps a handler to write asynchronously. For handlers made
+// with New the formatting happens when logging and only the writing
+// in the background, all others handle their records in the background.
+func Async(h slog.Handler, opts *AsyncOptions) *AsyncHandler {
+	if opts == nil {
+		opts = &AsyncOptions{}
+	}
+	size := opts.QueueSize
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+	q := &asyncQueue{
+		size:    size,
+		policy:  opts.Policy,
+		level:   opts.Level,
+		wake:    make(chan struct{}, 1),
+		stopped: make(chan struct{}),
+	}
+	go q.run()
+	a := &AsyncHandler{handler: h, queue: q}
+	a.text, _ = h.(*TextHandler)
+	return a
+}
+
+func (a *AsyncHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return a.handler.Enabled(ctx, level)
+}
+
+func (a *AsyncHandler) Handle(ctx context.Context, r slog.Record) error {
+	if a.text == nil {
+		return a.handleLater(ctx, r)
+	}
+	bufp := allocBuf()
+	*bufp = a.text.appendRecord(*bufp, r)
+	h, level := a.text, r.Level
+	return a.queue.push(ctx, level, asyncItem{text: h, buf: bufp, level: level})
+}
+
+// handleLater hands the record to a handler that isn't from New
+// in the background, apart from Handle so the record only escapes
+// to the heap for these
+func (a *AsyncHandler) handleLater(ctx context.Context, r slog.Record) error {
+	r = r.Clone()
+	h := a.handler
+	return a.queue.push(ctx, r.Level, asyncItem{write: func() error {
+		return h.Handle(context.Background(), r)
+	}})
+}
+
+func (a *AsyncHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return a.wrap(a.handler.WithAttrs(attrs))
+}
+
+func (a *AsyncHandler) WithGroup(name string) slog.Handler {
+	return a.wrap(a.handler.WithGroup(name))
+}
+
+func (a *AsyncHandler) wrap(h slog.Handler) *AsyncHandler {
+	a2 := &AsyncHandler{handler: h, queue: a.queue}
+	a2.text, _ = h.(*TextHandler)
+	return a2
+}
+
+// Dropped is the number of records dropped so far
+func (a *AsyncHandler) Dropped() uint64 {
+	q := a.queue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dropped
+}
+
+// Flush waits until everything logged before it is written or
+// dropped, or ctx is done. Handlers with a Flush method of their
+// own, like batching ones, are flushed then too. It returns the
+// first error writing since the last Flush.
+func (a *AsyncHandler) Flush(ctx context.Context) error {
+	q := a.queue
+	q.mu.Lock()
+	target := q.queued
+	for q.done < target {
+		progress := q.waitProgress()
+		q.mu.Unlock()
+		select {
+		case <-progress:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		q.mu.Lock()
+	}
+	err := q.err
+	q.err = nil
+	q.mu.Unlock()
+	if ferr := a.flushHandler(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// Close writes everything in the queue and stops the writing
+// goroutine, for all handlers sharing it. Records logged after
+// Close are not written. It returns the first error writing since
+// the last Flush. Use Flush with a deadline first if the writer
+// might hang.
+func (a *AsyncHandler) Close() error {
+	q := a.queue
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		q.wakeWriter()
+		q.signalProgress()
+	}
+	q.mu.Unlock()
+	<-q.stopped
+
+	q.mu.Lock()
+	err := q.err
+	q.err = nil
+	q.mu.Unlock()
+	if ferr := a.flushHandler(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+func (a *AsyncHandler) flushHandler() error {
+	if f, ok := a.handler.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// asyncItem is a record waiting in the queue, either formatted
+// already by text into buf, freed once it's written or dropped,
+// or handled by write
+type asyncItem struct {
+	write func() error
+	text  *TextHandler
+	buf   *[]byte
+	level slog.Level
+}
+
+func (it asyncItem) handle() error {
+	if it.text != nil {
+		return it.text.write(*it.buf, it.level)
+	}
+	return it.write()
+}
+
+func (it asyncItem) free() {
+	if it.buf != nil {
+		freeBuf(it.buf)
+	}
+}
+
+type asyncQueue struct {
+	mu sync.Mutex
+	// a ring, the queue is n items from head on. It grows up to
+	// size, and then is reused without allocating.
+	items   []asyncItem
+	head, n int
+	size    int
+	policy  DropPolicy
+	level   slog.Level
+	closed  bool
+	// wakes up the writing goroutine, one wakeup is kept
+	// in it while the goroutine is busy
+	wake chan struct{}
+	// closed when a record is done or the queue is closed, made by
+	// the first one waiting for that, so pushing and writing don't
+	// allocate while no one waits
+	progress chan struct{}
+	// closed when the writing goroutine is done
+	stopped chan struct{}
+
+	// records put in the queue, and written or dropped from it,
+	// the ones before the queued count when Flush is called
+	// have to be done for it to return
+	queued, done uint64
+	dropped      uint64
+	err          error
+}
+
+// wakeWriter wakes up the writing goroutine, the lock has to be held
+func (q *asyncQueue) wakeWriter() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+		// it's awake already or has a wakeup waiting
+	}
+}
+
+// waitProgress is closed on the next progress, the lock has to be held
+func (q *asyncQueue) waitProgress() <-chan struct{} {
+	if q.progress == nil {
+		q.progress = make(chan struct{})
+	}
+	return q.progress
+}
+
+// signalProgress wakes up everyone waiting, the lock has to be held
+func (q *asyncQueue) signalProgress() {
+	if q.progress != nil {
+		close(q.progress)
+		q.progress = nil
+	}
+}
+
+// push queues it, an item that isn't queued is freed right away
+func (q *asyncQueue) push(ctx context.Context, level slog.Level, it asyncItem) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for {
+		if q.closed {
+			it.free()
+			return ErrClosed
+		}
+		if q.n < q.size {
+			q.pushBack(it)
+			q.queued++
+			q.wakeWriter()
+			return nil
+		}
+		switch {
+		case q.policy == DropPolicyNewest,
+			q.policy == DropPolicyBelowLevel && level < q.level:
+			it.free()
+			q.dropped++
+			return nil
+		case q.policy == DropPolicyOldest:
+			q.popFront().free()
+			q.done++
+			q.dropped++
+			q.signalProgress()
+			continue
+		}
+		// wait for room
+		progress := q.waitProgress()
+		q.mu.Unlock()
+		select {
+		case <-progress:
+		case <-ctx.Done():
+			q.mu.Lock()
+			it.free()
+			q.dropped++
+			return ctx.Err()
+		}
+		q.mu.Lock()
+	}
+}
+
+// pushBack adds to the end of the queue, the lock has to be held
+func (q *asyncQueue) pushBack(it asyncItem) {
+	if q.n == len(q.items) {
+		grown := make([]asyncItem, min(max(2*len(q.items), 64), q.size))
+		for i := range q.n {
+			grown[i] = q.items[(q.head+i)%len(q.items)]
+		}
+		q.items, q.head = grown, 0
+	}
+	q.items[(q.head+q.n)%len(q.items)] = it
+	q.n++
+}
+
+// popFront takes the item waiting the longest, the lock has to be held
+func (q *asyncQueue) popFront() asyncItem {
+	it := q.items[q.head]
+	q.items[q.head] = asyncItem{}
+	q.head = (q.head + 1) % len(q.items)
+	q.n--
+	return it
+}
+
+// run writes the queued records one at a time, until
+// the queue is closed and empty
+func (q *asyncQueue) run() {
+	defer close(q.stopped)
+	q.mu.Lock()
+	for {
+		if q.n == 0 {
+			if q.closed {
+				q.mu.Unlock()
+				return
+			}
+			q.mu.Unlock()
+			<-q.wake
+			q.mu.Lock()
+			continue
+		}
+		it := q.popFront()
+		q.mu.Unlock()
+
+		err := it.handle()
+		it.free()
+
+		q.mu.Lock()
+		if err != nil && q.err == nil {
+			q.err = err
+		}
+		q.done++
+		q.signalProgress()
+	}
+}
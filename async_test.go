@@ -0,0 +1,220 @@
+package rainbow_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nerdwave-nick/rainbow"
+)
+
+// gatedWriter holds up every write until it is released
+type gatedWriter struct {
+	entered chan struct{}
+	release chan struct{}
+	mu      sync.Mutex
+	buf     bytes.Buffer
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{entered: make(chan struct{}, 100), release: make(chan struct{})}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	w.entered <- struct{}{}
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gatedWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestRainbow_AsyncPolicies(t *testing.T) {
+	tests := []struct {
+		Policy  rainbow.DropPolicy
+		Levels  []slog.Level
+		Output  string
+		Dropped uint64
+	}{
+		{
+			Policy:  rainbow.DropPolicyNewest,
+			Levels:  []slog.Level{slog.LevelInfo, slog.LevelInfo, slog.LevelInfo},
+			Output:  "INF 0\nINF 1\n",
+			Dropped: 1,
+		},
+		{
+			Policy:  rainbow.DropPolicyOldest,
+			Levels:  []slog.Level{slog.LevelInfo, slog.LevelInfo, slog.LevelInfo},
+			Output:  "INF 0\nINF 2\n",
+			Dropped: 1,
+		},
+		{
+			// the error waits for room, the debug record doesn't
+			Policy:  rainbow.DropPolicyBelowLevel,
+			Levels:  []slog.Level{slog.LevelInfo, slog.LevelInfo, slog.LevelDebug, slog.LevelError},
+			Output:  "INF 0\nINF 1\nERR 3\n",
+			Dropped: 1,
+		},
+		{
+			Policy: rainbow.DropPolicyBlock,
+			Levels: []slog.Level{slog.LevelInfo, slog.LevelInfo, slog.LevelInfo},
+			Output: "INF 0\nINF 1\nINF 2\n",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("async policy test %d", i), func(t *testing.T) {
+			w := newGatedWriter()
+			h := rainbow.Async(rainbow.New(w, &rainbow.Options{NoColor: true, Level: slog.LevelDebug, Layout: "{level} {msg}"}), &rainbow.AsyncOptions{
+				QueueSize: 1,
+				Policy:    tt.Policy,
+				Level:     slog.LevelWarn,
+			})
+			handleNoTime(t, h, tt.Levels[0], "0")
+			// the first record is being written, the next fills the queue
+			<-w.entered
+			var wg sync.WaitGroup
+			for n, level := range tt.Levels[1:] {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					handleNoTime(t, h, level, fmt.Sprint(n+1))
+				}()
+				if n == 0 {
+					// keep the order of the ones that fit
+					wg.Wait()
+				}
+			}
+			if tt.Policy != rainbow.DropPolicyBlock && tt.Policy != rainbow.DropPolicyBelowLevel {
+				wg.Wait()
+			}
+			if tt.Policy == rainbow.DropPolicyBelowLevel {
+				// the debug record is dropped before the error gets room
+				for h.Dropped() == 0 {
+					time.Sleep(time.Millisecond)
+				}
+			}
+			close(w.release)
+			wg.Wait()
+			if err := h.Flush(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if w.String() != tt.Output {
+				t.Errorf("output %q did not match the expected output %q", w.String(), tt.Output)
+			}
+			if h.Dropped() != tt.Dropped {
+				t.Errorf("dropped %d records instead of %d", h.Dropped(), tt.Dropped)
+			}
+			if err := h.Close(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestRainbow_AsyncFlushAndClose(t *testing.T) {
+	w := newGatedWriter()
+	h := rainbow.Async(rainbow.New(w, &rainbow.Options{NoColor: true, Layout: "{msg} {attrs}", AttrLayout: rainbow.AttrLayoutSingle}), &rainbow.AsyncOptions{QueueSize: 1})
+	handleNoTime(t, h, slog.LevelInfo, "a")
+	<-w.entered
+	handleNoTime(t, h, slog.LevelInfo, "b")
+
+	// the writer is stuck, so are the flush and the blocked record
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := h.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("flush returned %v instead of the deadline", err)
+	}
+	if err := h.Handle(ctx, slog.NewRecord(time.Time{}, slog.LevelInfo, "c", 0)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("handle returned %v instead of the deadline", err)
+	}
+	// given up on, so it counts as dropped
+	if h.Dropped() != 1 {
+		t.Errorf("dropped %d records instead of 1", h.Dropped())
+	}
+
+	// handlers made from it share the queue
+	h2 := h.WithGroup("g").WithAttrs([]slog.Attr{slog.Int("n", 1)})
+	close(w.release)
+	handleNoTime(t, h2, slog.LevelInfo, "d")
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "a\nb\nd g.n=1\n"
+	if w.String() != expected {
+		t.Errorf("output %q did not match the expected output %q", w.String(), expected)
+	}
+	if err := h2.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "e", 0)); !errors.Is(err, rainbow.ErrClosed) {
+		t.Errorf("handle after close returned %v instead of ErrClosed", err)
+	}
+}
+
+func TestRainbow_AsyncAllocs(t *testing.T) {
+	h := rainbow.Async(rainbow.New(io.Discard, nil), nil)
+	defer h.Close()
+	r := benchRecord()
+	// the queue grows and the buffers are pooled while warming up
+	for range 200 {
+		if err := h.Handle(context.Background(), r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := h.Handle(context.Background(), r); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("handling a record took %v allocations instead of none", allocs)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestRainbow_AsyncOtherHandlers(t *testing.T) {
+	buffer := bytes.NewBuffer(make([]byte, 0))
+	h := rainbow.Async(slog.NewTextHandler(buffer, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}), nil)
+	handleNoTime(t, h.WithAttrs([]slog.Attr{slog.Int("n", 1)}), slog.LevelInfo, "m")
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "level=INFO msg=m n=1\n"
+	if buffer.String() != expected {
+		t.Errorf("output %q did not match the expected output %q", buffer.String(), expected)
+	}
+
+	// write errors come out of Flush and Close
+	h = rainbow.Async(rainbow.New(failingWriter{}, nil), nil)
+	handleNoTime(t, h, slog.LevelInfo, "m")
+	if err := h.Flush(context.Background()); err == nil || err.Error() != "broken pipe" {
+		t.Errorf("flush returned %v instead of the write error", err)
+	}
+	if err := h.Close(); err != nil {
+		t.Errorf("close returned %v after the error was flushed", err)
+	}
+}
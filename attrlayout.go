@@ -0,0 +1,152 @@
+package rainbow
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/nerdwave-nick/rainbow/ansi"
+)
+
+type AttrLayout int
+
+const (
+	// every attr on a line of its own, using the separators from the options
+	AttrLayoutMulti AttrLayout = iota
+	// everything on one line, groups in braces like http={method="GET" status=200}
+	AttrLayoutSingle
+	// single line while it fits into Options.Width, multi line otherwise
+	AttrLayoutAuto
+)
+
+// width assumed when the terminal can't tell
+const defaultWidth = 80
+
+// termWidth is the terminal width a handler works with, shared by
+// its clones so they all follow resizes
+type termWidth struct {
+	width atomic.Int32
+	// the terminal asked again after it got resized, nil if the
+	// width stays the same
+	f *os.File
+	// resizes when the terminal was last asked
+	seen atomic.Uint64
+}
+
+// newWidth is the terminal width the handler works with, from the
+// options or the writer. A terminal keeps it up to date when resized.
+func newWidth(out io.Writer, opts *Options) *termWidth {
+	w := &termWidth{}
+	switch {
+	case opts.Width > 0:
+		w.width.Store(int32(opts.Width))
+	case opts.Wrap || opts.AttrLayout == AttrLayoutAuto:
+		if f, ok := out.(*os.File); ok && terminalWidth(f) > 0 {
+			w.f = f
+			w.seen.Store(resizes())
+		}
+		w.width.Store(int32(detectWidth(out)))
+	}
+	return w
+}
+
+// Load is the width, asking the terminal again if it got resized
+func (w *termWidth) Load() int {
+	if w.f != nil {
+		if n := resizes(); n != w.seen.Load() {
+			// clones asking at the same time both ask the terminal
+			w.seen.Store(n)
+			if width := terminalWidth(w.f); width > 0 {
+				w.width.Store(int32(width))
+			}
+		}
+	}
+	return int(w.width.Load())
+}
+
+// detectWidth finds the width of the terminal w writes to,
+// falling back to $COLUMNS and then defaultWidth
+func detectWidth(w io.Writer) int {
+	if f, ok := w.(*os.File); ok {
+		if n := terminalWidth(f); n > 0 {
+			return n
+		}
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return defaultWidth
+}
+
+// maxLineWidth is the visible width of the widest line in b
+func maxLineWidth(b []byte) int {
+	widest := 0
+	for len(b) > 0 {
+		line := b
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			line, b = b[:i], b[i+1:]
+		} else {
+			b = nil
+		}
+		widest = max(widest, ansi.Width(string(line)))
+	}
+	return widest
+}
+
+// appendWrapped wraps the lines of a record to the terminal width,
+// the first line hangs under the message, attr lines under their value
+func (h *TextHandler) appendWrapped(dst, buf []byte, hs *handleState) []byte {
+	width := h.width.Load()
+	start := 0
+	for i := 0; start <= len(buf); i++ {
+		end := bytes.IndexByte(buf[start:], '\n')
+		if end == -1 {
+			end = len(buf)
+		} else {
+			end += start
+		}
+		line := buf[start:end]
+		indent := 0
+		if i == 0 && hs.MessageStart <= len(line) {
+			indent = ansi.Width(string(line[:hs.MessageStart]))
+		} else {
+			indent = valueColumn(line)
+		}
+		if i > 0 {
+			dst = append(dst, '\n')
+		}
+		dst = ansi.AppendWrapped(dst, line, width, indent)
+		start = end + 1
+	}
+	return dst
+}
+
+// valueColumn is the column after the first = of a line,
+// or where the text starts on lines without one
+func valueColumn(line []byte) int {
+	col := 0
+	text := -1
+	found := -1
+	var p ansi.Parser
+	p.Feed(line, func(r ansi.Run) {
+		for b := r.Text; len(b) > 0 && found == -1; {
+			size, width := ansi.NextCluster(b)
+			switch c := b[0]; {
+			case c == '\t':
+				width = (col/ansi.TabWidth+1)*ansi.TabWidth - col
+			case c == '=':
+				found = col + 1
+			case text == -1 && c != ' ':
+				text = col
+			}
+			col += width
+			b = b[size:]
+		}
+	})
+	if found != -1 {
+		return found
+	}
+	return max(text, 0)
+}
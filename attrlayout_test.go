@@ -0,0 +1,112 @@
+package rainbow_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/nerdwave-nick/rainbow"
+)
+
+func TestRainbow_AttrLayout(t *testing.T) {
+	attrs := []slog.Attr{
+		slog.Group("http", slog.String("method", "GET"), slog.Group("res", slog.Int("status", 200))),
+		slog.Int("n", 1),
+	}
+	tests := []struct {
+		AttrLayout rainbow.AttrLayout
+		Layout     string
+		Width      int
+		Output     string
+	}{
+		{
+			AttrLayout: rainbow.AttrLayoutMulti,
+			Output:     "|INF m\n\tjob.id=7\n\tjob.http.method=\"GET\"\n\tjob.http.res.status=200\n\tjob.n=1\n",
+		},
+		{
+			AttrLayout: rainbow.AttrLayoutSingle,
+			Output:     "|INF m job.id=7 job.http={method=\"GET\" res={status=200}} job.n=1\n",
+		},
+		{
+			AttrLayout: rainbow.AttrLayoutAuto,
+			Width:      64,
+			Output:     "|INF m job.id=7 job.http={method=\"GET\" res={status=200}} job.n=1\n",
+		},
+		{
+			AttrLayout: rainbow.AttrLayoutAuto,
+			Width:      63,
+			Output:     "|INF m\n\tjob.id=7\n\tjob.http.method=\"GET\"\n\tjob.http.res.status=200\n\tjob.n=1\n",
+		},
+		{
+			// a layout of its own keeps the attrs in place
+			AttrLayout: rainbow.AttrLayoutMulti,
+			Layout:     "{attrs} | {level} {msg}",
+			Output:     "job.id=7\n\tjob.http.method=\"GET\"\n\tjob.http.res.status=200\n\tjob.n=1 | INF m\n",
+		},
+		{
+			AttrLayout: rainbow.AttrLayoutSingle,
+			Layout:     "{attrs} | {level} {msg}",
+			Output:     "job.id=7 job.http={method=\"GET\" res={status=200}} job.n=1 | INF m\n",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("attr layout test %d", i), func(t *testing.T) {
+			buffer := bytes.NewBuffer(make([]byte, 0))
+			h := rainbow.New(buffer, &rainbow.Options{
+				NoColor:    true,
+				Layout:     tt.Layout,
+				AttrLayout: tt.AttrLayout,
+				Width:      tt.Width,
+			}).WithGroup("job").WithAttrs([]slog.Attr{slog.Int("id", 7)})
+			handleNoTime(t, h, slog.LevelInfo, "m", attrs...)
+			if buffer.String() != tt.Output {
+				t.Errorf("output %q did not match the expected output %q", buffer.String(), tt.Output)
+			}
+		})
+	}
+}
+
+func TestRainbow_Wrap(t *testing.T) {
+	tests := []struct {
+		AttrLayout rainbow.AttrLayout
+		Message    string
+		Attrs      []slog.Attr
+		Output     string
+	}{
+		{
+			// long messages hang under the message column
+			Message: "the quick brown fox jumps over the lazy dog",
+			Output:  "|INF the quick brown\n     fox jumps over\n     the lazy dog\n",
+		},
+		{
+			// long values hang after the key, at most half the width in
+			Message: "m",
+			Attrs:   []slog.Attr{slog.String("text", "the quick brown fox jumps")},
+			Output:  "|INF m\n\ttext=\"the\n          quick\n          brown fox\n          jumps\"\n",
+		},
+		{
+			AttrLayout: rainbow.AttrLayoutSingle,
+			Message:    "m",
+			Attrs:      []slog.Attr{slog.Int("a", 1), slog.Int("b", 2), slog.Int("c", 3), slog.Int("d", 4), slog.Int("e", 5)},
+			Output:     "|INF m a=1 b=2 c=3\n     d=4 e=5\n",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("wrap test %d", i), func(t *testing.T) {
+			buffer := bytes.NewBuffer(make([]byte, 0))
+			h := rainbow.New(buffer, &rainbow.Options{
+				NoColor:    true,
+				AttrLayout: tt.AttrLayout,
+				Width:      20,
+				Wrap:       true,
+			})
+			handleNoTime(t, h, slog.LevelInfo, tt.Message, tt.Attrs...)
+			if buffer.String() != tt.Output {
+				t.Errorf("output %q did not match the expected output %q", buffer.String(), tt.Output)
+			}
+		})
+	}
+}
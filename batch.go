@@ -0,0 +1,183 @@
+package rainbow
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// BatchOptions collect records into larger writes, one write
+// per batch instead of one per record
+type BatchOptions struct {
+	// Size in bytes a batch is written at, defaults to 64KiB.
+	// Records larger than that are written right away.
+	Size int
+	// Interval a batch is written after at the latest,
+	// counted from its first record, defaults to a second
+	Interval time.Duration
+	// records at or above FlushLevel are written right away, along
+	// with everything before them, defaults to slog.LevelError
+	FlushLevel slog.Leveler
+}
+
+const (
+	defaultBatchSize     = 64 * 1024
+	defaultBatchInterval = time.Second
+)
+
+// batcher is shared by all clones, and locked with their lock
+type batcher struct {
+	lock *sync.Mutex
+	out  io.Writer
+
+	size       int
+	interval   time.Duration
+	flushLevel slog.Leveler
+
+	buf   []byte
+	timer *time.Timer
+	// counts the timers, so one of an earlier batch
+	// firing late doesn't write the next one early
+	timerGen uint64
+	// after Close records are written right away
+	closed bool
+	// error of a write from the timer,
+	// returned by the next write or Flush
+	err error
+}
+
+func compileBatch(opts *BatchOptions, out io.Writer, lock *sync.Mutex) *batcher {
+	if opts == nil {
+		return nil
+	}
+	b := &batcher{
+		lock:       lock,
+		out:        out,
+		size:       defaultBatchSize,
+		interval:   defaultBatchInterval,
+		flushLevel: opts.FlushLevel,
+	}
+	if opts.Size > 0 {
+		b.size = opts.Size
+	}
+	if opts.Interval > 0 {
+		b.interval = opts.Interval
+	}
+	if b.flushLevel == nil {
+		b.flushLevel = slog.LevelError
+	}
+	return b
+}
+
+// write adds a record to the batch, the lock has to be held
+func (b *batcher) write(p []byte, level slog.Level) error {
+	if b.closed || len(b.buf) == 0 && len(p) >= b.size {
+		return b.firstErr(b.writeOut(p))
+	}
+	if b.buf == nil {
+		b.buf = make([]byte, 0, b.size)
+	}
+	b.buf = append(b.buf, p...)
+	if len(b.buf) >= b.size || level >= b.flushLevel.Level() {
+		return b.flush()
+	}
+	if b.timer == nil {
+		b.timerGen++
+		gen := b.timerGen
+		b.timer = time.AfterFunc(b.interval, func() { b.tick(gen) })
+	}
+	return nil
+}
+
+// flush writes the batch, the lock has to be held
+func (b *batcher) flush() error {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if len(b.buf) == 0 {
+		return b.firstErr(nil)
+	}
+	err := b.writeOut(b.buf)
+	// the batch is gone either way, a broken
+	// writer shouldn't make it grow forever
+	b.buf = b.buf[:0]
+	return b.firstErr(err)
+}
+
+func (b *batcher) writeOut(p []byte) error {
+	_, err := b.out.Write(p)
+	return err
+}
+
+// firstErr returns the error of the timer before err
+func (b *batcher) firstErr(err error) error {
+	if b.err != nil {
+		err, b.err = b.err, nil
+	}
+	return err
+}
+
+func (b *batcher) tick(gen uint64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	// a flush may have come first, the timer is then of an older batch
+	if b.timer == nil || gen != b.timerGen {
+		return
+	}
+	b.timer = nil
+	if len(b.buf) == 0 {
+		return
+	}
+	if err := b.writeOut(b.buf); err != nil && b.err == nil {
+		b.err = err
+	}
+	b.buf = b.buf[:0]
+}
+
+// Flusher is a handler holding on to records before writing them,
+// like the ones from New with BatchOptions and Multi
+type Flusher interface {
+	// Flush writes the records waiting, and returns the
+	// first error writing since the last Flush
+	Flush() error
+}
+
+// Flush writes the records h is holding on to, if it's a Flusher
+// or an AsyncHandler. New returns a slog.Handler, this saves the
+// type assertion. Call it before exiting, or they may never be written.
+func Flush(h slog.Handler) error {
+	switch h := h.(type) {
+	case *AsyncHandler:
+		return h.Flush(context.Background())
+	case Flusher:
+		return h.Flush()
+	}
+	return nil
+}
+
+// Flush writes the records waiting in the batch, with BatchOptions.
+// Call it before exiting, or they may never be written.
+// It returns the first error writing since the last write or Flush.
+func (h *TextHandler) Flush() error {
+	if h.batch == nil {
+		return nil
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.batch.flush()
+}
+
+// Close flushes the batch and stops its timer, for all handlers
+// sharing it. Records logged after Close are written right away.
+func (h *TextHandler) Close() error {
+	if h.batch == nil {
+		return nil
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.batch.closed = true
+	return h.batch.flush()
+}
@@ -0,0 +1,169 @@
+package rainbow_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nerdwave-nick/rainbow"
+)
+
+// writesRecorder keeps every write apart
+type writesRecorder struct {
+	mu     sync.Mutex
+	writes []string
+	err    error
+}
+
+func (w *writesRecorder) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes = append(w.writes, string(p))
+	return len(p), w.err
+}
+
+func (w *writesRecorder) Writes() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.writes...)
+}
+
+func TestRainbow_Batch(t *testing.T) {
+	tests := []struct {
+		Batch    rainbow.BatchOptions
+		Levels   []slog.Level
+		Messages []string
+		Writes   []string
+	}{
+		{
+			// nothing reaches the size
+			Batch:    rainbow.BatchOptions{Size: 100, Interval: time.Hour},
+			Levels:   []slog.Level{slog.LevelInfo, slog.LevelWarn},
+			Messages: []string{"one", "two"},
+			Writes:   nil,
+		},
+		{
+			Batch:    rainbow.BatchOptions{Size: 8, Interval: time.Hour},
+			Levels:   []slog.Level{slog.LevelInfo, slog.LevelInfo, slog.LevelInfo},
+			Messages: []string{"one", "two", "six"},
+			Writes:   []string{"one\ntwo\n"},
+		},
+		{
+			// too large for a batch of its own
+			Batch:    rainbow.BatchOptions{Size: 8, Interval: time.Hour},
+			Levels:   []slog.Level{slog.LevelInfo, slog.LevelInfo},
+			Messages: []string{"a long message", "one"},
+			Writes:   []string{"a long message\n"},
+		},
+		{
+			Batch:    rainbow.BatchOptions{Size: 100, Interval: time.Hour},
+			Levels:   []slog.Level{slog.LevelInfo, slog.LevelError, slog.LevelInfo},
+			Messages: []string{"one", "two", "six"},
+			Writes:   []string{"one\ntwo\n"},
+		},
+		{
+			Batch:    rainbow.BatchOptions{Size: 100, Interval: time.Hour, FlushLevel: slog.LevelWarn},
+			Levels:   []slog.Level{slog.LevelInfo, slog.LevelWarn, slog.LevelInfo},
+			Messages: []string{"one", "two", "six"},
+			Writes:   []string{"one\ntwo\n"},
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("batch test %d", i), func(t *testing.T) {
+			w := &writesRecorder{}
+			h := rainbow.New(w, &rainbow.Options{NoColor: true, Layout: "{msg}", Batch: &tt.Batch})
+			for n, msg := range tt.Messages {
+				handleNoTime(t, h, tt.Levels[n], msg)
+			}
+			writes := w.Writes()
+			if fmt.Sprint(writes) != fmt.Sprint(tt.Writes) {
+				t.Errorf("writes %q did not match the expected writes %q", writes, tt.Writes)
+			}
+			if err := rainbow.Flush(h); err != nil {
+				t.Fatal(err)
+			}
+			all := strings.Join(w.Writes(), "")
+			expected := strings.Join(tt.Messages, "\n") + "\n"
+			if all != expected {
+				t.Errorf("output %q did not match the expected output %q", all, expected)
+			}
+		})
+	}
+}
+
+func TestRainbow_BatchClones(t *testing.T) {
+	w := &writesRecorder{}
+	h := rainbow.New(w, &rainbow.Options{NoColor: true, Layout: "{msg}", Batch: &rainbow.BatchOptions{Interval: time.Hour}})
+	h2 := h.WithAttrs([]slog.Attr{slog.Int("n", 1)}).WithGroup("g")
+
+	var wg sync.WaitGroup
+	for i := range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i%2 == 0 {
+				handleNoTime(t, h, slog.LevelInfo, "one")
+			} else {
+				handleNoTime(t, h2, slog.LevelInfo, "two")
+			}
+		}()
+	}
+	wg.Wait()
+	// any of them flushes the batch of all
+	if err := rainbow.Flush(h2); err != nil {
+		t.Fatal(err)
+	}
+	writes := w.Writes()
+	if len(writes) != 1 {
+		t.Fatalf("the records took %d writes instead of one", len(writes))
+	}
+	if strings.Count(writes[0], "one\n") != 50 || strings.Count(writes[0], "two\n") != 50 {
+		t.Errorf("output %q is missing records", writes[0])
+	}
+}
+
+func TestRainbow_BatchInterval(t *testing.T) {
+	w := &writesRecorder{err: errors.New("broken pipe")}
+	h := rainbow.New(w, &rainbow.Options{NoColor: true, Layout: "{msg}", Batch: &rainbow.BatchOptions{Interval: time.Millisecond}})
+	handleNoTime(t, h, slog.LevelInfo, "one")
+	handleNoTime(t, h, slog.LevelInfo, "two")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(w.Writes()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	expected := []string{"one\ntwo\n"}
+	if writes := w.Writes(); fmt.Sprint(writes) != fmt.Sprint(expected) {
+		t.Errorf("writes %q did not match the expected writes %q", writes, expected)
+	}
+	// the error of the timer comes out of the next Flush
+	if err := rainbow.Flush(h); err == nil || err.Error() != "broken pipe" {
+		t.Errorf("flush returned %v instead of the write error", err)
+	}
+	if err := rainbow.Flush(h); err != nil {
+		t.Errorf("flush returned %v after the error was returned", err)
+	}
+}
+
+func TestRainbow_BatchClose(t *testing.T) {
+	w := &writesRecorder{}
+	h := rainbow.New(w, &rainbow.Options{NoColor: true, Layout: "{msg}", Batch: &rainbow.BatchOptions{Interval: time.Hour}})
+	handleNoTime(t, h, slog.LevelInfo, "one")
+	handleNoTime(t, h, slog.LevelInfo, "two")
+	if err := h.(io.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+	// after Close there's no batch left to wait in
+	handleNoTime(t, h, slog.LevelInfo, "three")
+
+	expected := []string{"one\ntwo\n", "three\n"}
+	if writes := w.Writes(); fmt.Sprint(writes) != fmt.Sprint(expected) {
+		t.Errorf("writes %q did not match the expected writes %q", writes, expected)
+	}
+}
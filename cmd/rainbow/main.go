@@ -0,0 +1,39 @@
+// Command rainbow has tools around the rainbow handler.
+//
+//	rainbow themes [flags] [theme ...]
+//
+// prints sample output for the given themes, all of them by default.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `usage: rainbow <command> [flags]
+
+commands:
+  themes    preview themes and print their config
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	switch args[0] {
+	case "themes":
+		return runThemes(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "rainbow: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+}
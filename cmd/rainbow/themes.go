@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nerdwave-nick/rainbow"
+	"github.com/nerdwave-nick/rainbow/ansi"
+)
+
+func runThemes(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("themes", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, "usage: rainbow themes [flags] [theme ...]\n\n"+
+			"Prints sample output for the named themes, all of them if none are named.\n\n")
+		fs.PrintDefaults()
+	}
+	var files []string
+	fs.Func("f", "load a theme from a JSON `file`, can be repeated", func(s string) error {
+		files = append(files, s)
+		return nil
+	})
+	compare := fs.Bool("compare", false, "show two themes side by side")
+	config := fs.String("config", "", "print the config of the themes instead, as `go` or json")
+	list := fs.Bool("list", false, "only list the names of the themes")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	available := rainbow.Themes()
+	for _, f := range files {
+		t, err := loadThemeFile(f)
+		if err != nil {
+			fmt.Fprintf(stderr, "rainbow: %v\n", err)
+			return 1
+		}
+		available = append(available, t)
+	}
+
+	if *list {
+		for _, t := range available {
+			fmt.Fprintln(stdout, t.Name)
+		}
+		return 0
+	}
+
+	themes := available
+	if fs.NArg() > 0 {
+		themes = themes[:0:0]
+		for _, name := range fs.Args() {
+			t, ok := findTheme(available, name)
+			if !ok {
+				fmt.Fprintf(stderr, "rainbow: unknown theme %q, see rainbow themes -list\n", name)
+				return 1
+			}
+			themes = append(themes, t)
+		}
+	}
+
+	switch *config {
+	case "":
+	case "go", "json":
+		for i, t := range themes {
+			if i > 0 {
+				fmt.Fprintln(stdout)
+			}
+			if *config == "go" {
+				fmt.Fprintf(stdout, "// %s\n%s", t.Name, t.GoConfig())
+			} else {
+				fmt.Fprint(stdout, t.JSON())
+			}
+		}
+		return 0
+	default:
+		fmt.Fprintf(stderr, "rainbow: -config has to be go or json, not %q\n", *config)
+		return 2
+	}
+
+	if *compare {
+		if len(themes) != 2 {
+			fmt.Fprintln(stderr, "rainbow: -compare needs exactly two themes")
+			return 2
+		}
+		left, right := renderSample(themes[0]), renderSample(themes[1])
+		fmt.Fprint(stdout, sideBySide(themes[0].Name, left, themes[1].Name, right))
+		return 0
+	}
+
+	for i, t := range themes {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		fmt.Fprintf(stdout, "── %s ──\n%s", t.Name, renderSample(t))
+	}
+	return 0
+}
+
+func loadThemeFile(name string) (*rainbow.Theme, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t, err := rainbow.LoadTheme(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if t.Name == "" {
+		t.Name = strings.TrimSuffix(name, ".json")
+	}
+	return t, nil
+}
+
+// findTheme prefers the last theme with the name,
+// so loaded themes win over the built in ones
+func findTheme(themes []*rainbow.Theme, name string) (*rainbow.Theme, bool) {
+	for i := len(themes) - 1; i >= 0; i-- {
+		if strings.EqualFold(themes[i].Name, name) {
+			return themes[i], true
+		}
+	}
+	return nil, false
+}
+
+// the sample always shows the same time, so runs can be compared
+var sampleTime = time.Date(2024, 3, 14, 15, 9, 26, 0, time.Local)
+
+// renderSample logs a bit of everything a theme colors:
+// every level, every kind of value, nested groups, errors and long values
+func renderSample(t *rainbow.Theme) string {
+	var out bytes.Buffer
+	h := rainbow.New(&out, t.Apply(&rainbow.Options{Level: slog.LevelDebug}))
+	ctx := context.Background()
+	handle := func(h slog.Handler, level slog.Level, msg string, attrs ...slog.Attr) {
+		r := slog.NewRecord(sampleTime, level, msg, 0)
+		r.AddAttrs(attrs...)
+		_ = h.Handle(ctx, r)
+	}
+
+	handle(h, slog.LevelDebug, "cache lookup",
+		slog.String("key", "user:42"),
+		slog.Bool("hit", false),
+	)
+	handle(h, slog.LevelInfo, "request served",
+		slog.Group("http",
+			slog.String("method", "GET"),
+			slog.Int("status", 200),
+			slog.Duration("took", 1500*time.Microsecond),
+		),
+		slog.Uint64("bytes", 5120),
+		slog.Float64("ratio", 0.25),
+	)
+	handle(h, slog.LevelWarn, "clock skew",
+		slog.Int("offset", -3),
+		slog.Time("seen", sampleTime.Add(-time.Minute)),
+		slog.Any("peers", []string{"a", "b"}),
+	)
+	job := h.WithGroup("job").WithAttrs([]slog.Attr{slog.String("id", "7f3a")}).WithGroup("owner")
+	handle(job, slog.LevelError, "upload failed",
+		slog.String("name", "nick"),
+		slog.Any("err", errors.New("connection reset by peer")),
+	)
+	handle(h, slog.LevelInfo, "long value",
+		slog.String("body", strings.Repeat("lorem ipsum dolor sit amet ", 3)),
+	)
+	return out.String()
+}
+
+// sideBySide puts two samples next to each other, padding the
+// left one by its visible width. Styles running over into the next
+// line are closed at the end of it and opened again after.
+func sideBySide(leftName, left, rightName, right string) string {
+	ls := columnLines(leftName, left)
+	rs := columnLines(rightName, right)
+	width := 0
+	for _, l := range ls {
+		width = max(width, ansi.Width(l))
+	}
+	var sb strings.Builder
+	for i := 0; i < max(len(ls), len(rs)); i++ {
+		l, r := "", ""
+		if i < len(ls) {
+			l = ls[i]
+		}
+		if i < len(rs) {
+			r = rs[i]
+		}
+		sb.WriteString(l)
+		sb.WriteString(strings.Repeat(" ", width-ansi.Width(l)))
+		sb.WriteString(" │ ")
+		sb.WriteString(r)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// columnLines splits a sample into lines under a title,
+// each one starting and ending without a style
+func columnLines(name, sample string) []string {
+	lines := []string{"── " + name + " ──"}
+	var p ansi.Parser
+	for _, l := range strings.Split(strings.TrimSuffix(sample, "\n"), "\n") {
+		open := p.Style()
+		p.Feed([]byte(l), func(ansi.Run) {})
+		l = open.String() + expandTabs(l)
+		if !p.Style().IsZero() {
+			l += "\x1b[0m"
+		}
+		lines = append(lines, l)
+	}
+	return lines
+}
+
+// expandTabs replaces tabs with spaces up to the next multiple of 8,
+// they would line up differently once the line doesn't start the row
+func expandTabs(line string) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+	var sb strings.Builder
+	col := 0
+	for _, part := range strings.SplitAfter(line, "\t") {
+		text, tab := strings.CutSuffix(part, "\t")
+		sb.WriteString(text)
+		col += ansi.Width(text)
+		if tab {
+			next := (col/8 + 1) * 8
+			sb.WriteString(strings.Repeat(" ", next-col))
+			col = next
+		}
+	}
+	return sb.String()
+}
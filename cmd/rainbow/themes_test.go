@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nerdwave-nick/rainbow/ansi"
+)
+
+func TestThemes_Compare(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"themes", "-compare", "default", "ocean"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	column := -1
+	for _, l := range lines {
+		plain := ansi.Strip(l)
+		at := ansi.Width(plain[:strings.Index(plain, "│")])
+		if column == -1 {
+			column = at
+		}
+		if at != column {
+			t.Errorf("divider of line %q at column %d, not %d", plain, at, column)
+		}
+		if strings.Contains(l, "\t") {
+			t.Errorf("line %q still has tabs", l)
+		}
+	}
+	if !strings.HasPrefix(lines[0], "── default ──") || !strings.Contains(lines[0], "│ ── ocean ──") {
+		t.Errorf("header %q did not name both themes", lines[0])
+	}
+}
+
+func TestThemes_UnknownTheme(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"themes", "nope"}, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), `"nope"`) {
+		t.Errorf("exit code %d, stderr %q", code, stderr.String())
+	}
+}
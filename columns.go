@@ -0,0 +1,256 @@
+package rainbow
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/nerdwave-nick/rainbow/ansi"
+)
+
+// ColumnOptions lines up single line records with the ones before
+// them, so the attrs don't jump around while tailing a log
+type ColumnOptions struct {
+	// MessageWidth pads messages to a fixed width. Zero adapts it
+	// to the widest message of the last Window records.
+	MessageWidth int
+	// MaxWidth caps the adapting widths, defaults to 48
+	MaxWidth int
+	// Window is the number of records the widths adapt to, defaults to 20
+	Window int
+	// Pinned attrs are written before all others,
+	// each in a column of its own
+	Pinned []PinnedKey
+}
+
+type PinnedKey struct {
+	// Key is a key name, a glob or a dotted group path,
+	// the same as in NumberFormat.Keys. Only attrs outside of
+	// inline groups are pinned.
+	Key string
+	// Width of the column, zero adapts it like the message
+	Width int
+}
+
+const (
+	defaultColumnMaxWidth = 48
+	defaultColumnWindow   = 20
+)
+
+type columns struct {
+	messageWidth int
+	maxWidth     int
+	window       int
+	pinned       []pinnedColumn
+
+	// the rest is shared by all clones of the handler, behind its lock
+	state *columnState
+}
+
+type pinnedColumn struct {
+	key   keyPattern
+	width int
+}
+
+type columnState struct {
+	message widthTracker
+	level   widthTracker
+	pinned  []widthTracker
+}
+
+// widthTracker remembers the widths of the last records
+type widthTracker struct {
+	recent []int
+	next   int
+}
+
+// observe adds the width of a record and returns the widest of the window
+func (t *widthTracker) observe(width, window int) int {
+	if len(t.recent) < window {
+		t.recent = append(t.recent, width)
+	} else {
+		t.recent[t.next] = width
+		t.next = (t.next + 1) % window
+	}
+	widest := 0
+	for _, w := range t.recent {
+		widest = max(widest, w)
+	}
+	return widest
+}
+
+func compileColumns(opts *ColumnOptions) *columns {
+	if opts == nil {
+		return nil
+	}
+	c := &columns{
+		messageWidth: opts.MessageWidth,
+		maxWidth:     defaultColumnMaxWidth,
+		window:       defaultColumnWindow,
+		state:        &columnState{pinned: make([]widthTracker, len(opts.Pinned))},
+	}
+	if opts.MaxWidth > 0 {
+		c.maxWidth = opts.MaxWidth
+	}
+	if opts.Window > 0 {
+		c.window = opts.Window
+	}
+	for _, p := range opts.Pinned {
+		c.pinned = append(c.pinned, pinnedColumn{key: compileKeyPatterns([]string{p.Key})[0], width: p.Width})
+	}
+	return c
+}
+
+// attrRef points at a top level attr, in hs.Context or in the
+// record for ctx == len(hs.Context)
+type attrRef struct {
+	ctx, i int
+}
+
+// eachTopAttr calls f for the attrs from WithAttrs and the record,
+// outside of inline groups, in the order they are written
+func (h *TextHandler) eachTopAttr(r slog.Record, hs *handleState, f func(ref attrRef, groups []string, a slog.Attr)) {
+	for c, ca := range hs.Context {
+		for i, a := range ca.Attrs {
+			f(attrRef{c, i}, ca.Groups, a)
+		}
+	}
+	i := 0
+	r.Attrs(func(a slog.Attr) bool {
+		a.Value = a.Value.Resolve()
+		f(attrRef{len(hs.Context), i}, hs.Groups, a)
+		i++
+		return true
+	})
+}
+
+// fillColumns works out the column widths of a single line record,
+// taking the lock only to update the shared widths
+func (h *TextHandler) fillColumns(r slog.Record, hs *handleState) {
+	c := h.columns
+	messageWidth := min(ansi.Width(r.Message), c.maxWidth)
+	levelWidth := 0
+	if f, ok := h.layoutField(layoutLevel); ok && f.width == 0 {
+		// icons and badges included
+		levelWidth = min(ansi.Width(string(h.appendRecordLevel(nil, r.Level, f.text, hs))), c.maxWidth)
+	}
+
+	// render the pinned attrs, the first attr matching a key goes into its column
+	hs.Pinned = make([]string, len(c.pinned))
+	hs.PinnedRefs = make([]attrRef, len(c.pinned))
+	widths := make([]int, len(c.pinned))
+	for i := range hs.PinnedRefs {
+		hs.PinnedRefs[i] = attrRef{-1, -1}
+	}
+	h.eachTopAttr(r, hs, func(ref attrRef, groups []string, a slog.Attr) {
+		if a.Equal(slog.Attr{}) {
+			return
+		}
+		for i, p := range c.pinned {
+			if hs.PinnedRefs[i].ctx != -1 || !p.key.match(groups, a.Key) {
+				continue
+			}
+			hss := hs.clone()
+			hss.Groups = groups
+			hss.CurrentGroupName = h.groupPrefix(groups, hs)
+			hs.Pinned[i] = string(h.appendAttr(nil, a, hss))
+			hs.PinnedRefs[i] = ref
+			widths[i] = min(ansi.Width(hs.Pinned[i]), c.maxWidth)
+			return
+		}
+	})
+
+	h.lock.Lock()
+	if c.messageWidth == 0 {
+		messageWidth = c.state.message.observe(messageWidth, c.window)
+	}
+	levelWidth = c.state.level.observe(levelWidth, c.window)
+	for i, p := range c.pinned {
+		if p.width == 0 {
+			widths[i] = c.state.pinned[i].observe(widths[i], c.window)
+		}
+	}
+	h.lock.Unlock()
+
+	if c.messageWidth > 0 {
+		messageWidth = c.messageWidth
+	}
+	hs.MessageWidth = messageWidth
+	hs.LevelWidth = levelWidth
+	for i, p := range c.pinned {
+		width := widths[i]
+		if p.width > 0 {
+			width = p.width
+		}
+		if n := width - ansi.Width(hs.Pinned[i]); n > 0 {
+			hs.Pinned[i] += strings.Repeat(" ", n)
+		}
+	}
+}
+
+// appendPinnedAttrs writes the pinned columns first and then
+// the rest of the attrs, all on one line
+func (h *TextHandler) appendPinnedAttrs(buf []byte, r slog.Record, hs *handleState) []byte {
+	pinned := func(ref attrRef) bool {
+		for _, p := range hs.PinnedRefs {
+			if p == ref {
+				return true
+			}
+		}
+		return false
+	}
+	// columns at the end of the line aren't padded
+	last := -1
+	for i, ref := range hs.PinnedRefs {
+		if ref.ctx != -1 {
+			last = i
+		}
+	}
+	h.eachTopAttr(r, hs, func(ref attrRef, _ []string, a slog.Attr) {
+		if !a.Equal(slog.Attr{}) && !pinned(ref) {
+			last = len(hs.Pinned)
+		}
+	})
+
+	first := true
+	separate := func() {
+		if !first {
+			buf = append(buf, h.attrSeparator(hs)...)
+		}
+		first = false
+	}
+	for i, p := range hs.Pinned {
+		if p == "" || i > last {
+			// a key the record doesn't have is already spaces of its
+			// tracked width, so the columns after it stay in place.
+			// only one with no width in the window, or nothing
+			// after it, is left out.
+			continue
+		}
+		separate()
+		if i == last {
+			p = strings.TrimRight(p, " ")
+		}
+		buf = append(buf, p...)
+	}
+	h.eachTopAttr(r, hs, func(ref attrRef, groups []string, a slog.Attr) {
+		if a.Equal(slog.Attr{}) || pinned(ref) {
+			return
+		}
+		separate()
+		hss := hs.clone()
+		hss.Groups = groups
+		hss.CurrentGroupName = h.groupPrefix(groups, hs)
+		buf = h.appendAttr(buf, a, hss)
+	})
+	return buf
+}
+
+// layoutField finds the first field of a kind in the layout
+func (h *TextHandler) layoutField(kind layoutFieldKind) (layoutField, bool) {
+	for _, f := range h.layout {
+		if f.kind == kind {
+			return f, true
+		}
+	}
+	return layoutField{}, false
+}
@@ -0,0 +1,116 @@
+package rainbow_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/nerdwave-nick/rainbow"
+)
+
+func TestRainbow_Columns(t *testing.T) {
+	buffer := bytes.NewBuffer(make([]byte, 0))
+	h := rainbow.New(buffer, &rainbow.Options{
+		NoColor:    true,
+		Layout:     "{level:long} {msg} {attrs}",
+		AttrLayout: rainbow.AttrLayoutSingle,
+		Columns: &rainbow.ColumnOptions{
+			MaxWidth: 10,
+			Window:   2,
+			Pinned: []rainbow.PinnedKey{
+				{Key: "req"},
+				{Key: "user", Width: 8},
+			},
+		},
+	})
+	handleNoTime(t, h, slog.LevelInfo, "hello", slog.String("user", "x"), slog.Int("n", 1), slog.Int("req", 7))
+	handleNoTime(t, h, slog.LevelDebug, "hi", slog.Int("n", 2))
+	// the pinned key comes from the context
+	handleNoTime(t, h.WithAttrs([]slog.Attr{slog.Int("req", 10)}), slog.LevelInfo, "x", slog.Int("n", 3))
+	handleNoTime(t, h, slog.LevelInfo, "x", slog.Int("req", 1))
+	// longer than the maximum, not padded
+	handleNoTime(t, h, slog.LevelInfo, "a very long message", slog.Int("req", 1))
+	handleNoTime(t, h, slog.LevelInfo, "x", slog.Int("req", 1))
+
+	expected := []string{
+		`INFO hello req=7 user="x" n=1`,
+		`DEBUG hi                   n=2`,
+		`INFO  x  req=10          n=3`,
+		`INFO x req=1`,
+		`INFO a very long message req=1`,
+		`INFO x          req=1`,
+	}
+	actual := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	if len(actual) != len(expected) {
+		t.Fatalf("output %q did not have %d lines", buffer.String(), len(expected))
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("line %d %q did not match the expected line %q", i, actual[i], expected[i])
+		}
+	}
+}
+
+func TestRainbow_ColumnsFixed(t *testing.T) {
+	buffer := bytes.NewBuffer(make([]byte, 0))
+	h := rainbow.New(buffer, &rainbow.Options{
+		NoColor:    true,
+		AttrLayout: rainbow.AttrLayoutSingle,
+		Columns:    &rainbow.ColumnOptions{MessageWidth: 8},
+	})
+	handleNoTime(t, h, slog.LevelInfo, "a", slog.Int("n", 1))
+	handleNoTime(t, h, slog.LevelInfo, "abcdefghij", slog.Int("n", 2))
+	expected := "|INF a        n=1\n|INF abcdefghij n=2\n"
+	if buffer.String() != expected {
+		t.Errorf("output %q did not match the expected output %q", buffer.String(), expected)
+	}
+}
+
+func TestRainbow_ColumnsMissingPinned(t *testing.T) {
+	buffer := bytes.NewBuffer(make([]byte, 0))
+	h := rainbow.New(buffer, &rainbow.Options{
+		NoColor:    true,
+		Layout:     "{msg} {attrs}",
+		AttrLayout: rainbow.AttrLayoutSingle,
+		Columns: &rainbow.ColumnOptions{
+			MessageWidth: 1,
+			Pinned: []rainbow.PinnedKey{
+				{Key: "a"},
+				{Key: "b"},
+				{Key: "c"},
+			},
+		},
+	})
+	handleNoTime(t, h, slog.LevelInfo, "x", slog.Int("a", 1), slog.Int("b", 2), slog.Int("c", 3))
+	// the middle column keeps its space
+	handleNoTime(t, h, slog.LevelInfo, "x", slog.Int("a", 1), slog.Int("c", 3), slog.Int("n", 4))
+	// even when there hasn't been a b yet
+	h2 := rainbow.New(buffer, &rainbow.Options{
+		NoColor:    true,
+		Layout:     "{msg} {attrs}",
+		AttrLayout: rainbow.AttrLayoutSingle,
+		Columns: &rainbow.ColumnOptions{
+			MessageWidth: 1,
+			Pinned:       []rainbow.PinnedKey{{Key: "a"}, {Key: "b", Width: 3}, {Key: "c"}},
+		},
+	})
+	handleNoTime(t, h2, slog.LevelInfo, "x", slog.Int("a", 1), slog.Int("c", 3))
+	handleNoTime(t, h2, slog.LevelInfo, "x", slog.Int("a", 1), slog.Int("b", 2), slog.Int("c", 3))
+
+	expected := []string{
+		`x a=1 b=2 c=3`,
+		`x a=1     c=3 n=4`,
+		`x a=1     c=3`,
+		`x a=1 b=2 c=3`,
+	}
+	actual := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	if len(actual) != len(expected) {
+		t.Fatalf("output %q did not have %d lines", buffer.String(), len(expected))
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("line %d %q did not match the expected line %q", i, actual[i], expected[i])
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package rainbow
+
+import (
+	"bytes"
+	"log/slog"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type NumberFormatKind int
+
+const (
+	// 1.4 MB, powers of 1000
+	NumberFormatBytesSI NumberFormatKind = iota
+	// 1.4 MiB, powers of 1024
+	NumberFormatBytesIEC
+	// 1,234,567.89
+	NumberFormatThousands
+	// 1234567.89
+	NumberFormatFixed
+	// 0.25 -> 25.0%
+	NumberFormatPercent
+	// 1.5s, rounded to NumberFormat.Round
+	NumberFormatDuration
+)
+
+// NumberFormat changes how int, uint, float and duration values are
+// printed for the keys it matches.
+type NumberFormat struct {
+	// Keys are key names or path.Match globs like "*_bytes".
+	// Patterns containing a dot are matched against the full
+	// group path of the key instead, e.g. "http.response.size".
+	Keys []string
+	Kind NumberFormatKind
+	// Precision is the number of digits after the decimal point.
+	// Zero picks a default for the kind (1 for sizes and percentages,
+	// 2 for fixed), negative values mean no decimals at all.
+	Precision int
+	// Separator used between thousands, defaults to ",".
+	Separator string
+	// Unit is what a plain number stands for when formatted as a
+	// duration, e.g. time.Millisecond for a "latency_ms" key.
+	// Defaults to time.Nanosecond.
+	Unit time.Duration
+	// Round is the unit durations get rounded to, e.g. time.Millisecond.
+	Round time.Duration
+	// ShowRaw keeps the unformatted value in a dim suffix.
+	ShowRaw bool
+}
+
+type numberFormat struct {
+	keys      []keyPattern
+	kind      NumberFormatKind
+	precision int
+	separator string
+	unit      time.Duration
+	round     time.Duration
+	showRaw   bool
+}
+
+func compileNumberFormats(formats []NumberFormat) []numberFormat {
+	compiled := make([]numberFormat, 0, len(formats))
+	for _, f := range formats {
+		nf := numberFormat{
+			keys:      compileKeyPatterns(f.Keys),
+			kind:      f.Kind,
+			precision: f.Precision,
+			separator: f.Separator,
+			unit:      f.Unit,
+			round:     f.Round,
+			showRaw:   f.ShowRaw,
+		}
+		if nf.precision == 0 {
+			switch nf.kind {
+			case NumberFormatFixed:
+				nf.precision = 2
+			default:
+				nf.precision = 1
+			}
+		}
+		if nf.precision < 0 {
+			nf.precision = 0
+		}
+		if nf.separator == "" {
+			nf.separator = ","
+		}
+		if nf.unit <= 0 {
+			nf.unit = time.Nanosecond
+		}
+		compiled = append(compiled, nf)
+	}
+	return compiled
+}
+
+// keyPattern matches an attribute key, either by name or by its
+// dotted group path when the pattern itself contains a dot
+type keyPattern struct {
+	pattern string
+	dotted  bool
+	glob    bool
+}
+
+func compileKeyPatterns(patterns []string) []keyPattern {
+	compiled := make([]keyPattern, 0, len(patterns))
+	for _, p := range patterns {
+		kp := keyPattern{
+			pattern: p,
+			dotted:  strings.Contains(p, "."),
+			glob:    strings.ContainsAny(p, `*?[\`),
+		}
+		// broken globs are treated as plain names
+		if _, err := path.Match(p, ""); err != nil {
+			kp.glob = false
+		}
+		compiled = append(compiled, kp)
+	}
+	return compiled
+}
+
+func (kp *keyPattern) match(groups []string, key string) bool {
+	name := key
+	if kp.dotted {
+		name = strings.Join(append(groups[:len(groups):len(groups)], key), ".")
+	}
+	if !kp.glob {
+		return kp.pattern == name
+	}
+	ok, _ := path.Match(kp.pattern, name)
+	return ok
+}
+
+func matchAnyKey(patterns []keyPattern, groups []string, key string) bool {
+	for i := range patterns {
+		if patterns[i].match(groups, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *TextHandler) numberFormatFor(groups []string, key string) *numberFormat {
+	for i := range h.numberFormats {
+		if matchAnyKey(h.numberFormats[i].keys, groups, key) {
+			return &h.numberFormats[i]
+		}
+	}
+	return nil
+}
+
+// appendValue writes a numeric or duration value according to the format
+func (nf *numberFormat) appendValue(buf []byte, val slog.Value) []byte {
+	var v float64
+	switch val.Kind() {
+	case slog.KindInt64:
+		v = float64(val.Int64())
+	case slog.KindUint64:
+		v = float64(val.Uint64())
+	case slog.KindFloat64:
+		v = val.Float64()
+	case slog.KindDuration:
+		v = float64(val.Duration())
+	}
+	// nothing to scale or group in these
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return strconv.AppendFloat(buf, v, 'g', -1, 64)
+	}
+	switch nf.kind {
+	case NumberFormatBytesSI:
+		return appendByteSize(buf, v, 1000, siUnits, nf.precision)
+	case NumberFormatBytesIEC:
+		return appendByteSize(buf, v, 1024, iecUnits, nf.precision)
+	case NumberFormatThousands:
+		var scratch [64]byte
+		digits := scratch[:0]
+		switch val.Kind() {
+		// integers keep all their digits
+		case slog.KindInt64:
+			digits = strconv.AppendInt(digits, val.Int64(), 10)
+		case slog.KindUint64:
+			digits = strconv.AppendUint(digits, val.Uint64(), 10)
+		default:
+			digits = strconv.AppendFloat(digits, v, 'f', nf.precision, 64)
+		}
+		return appendThousands(buf, digits, nf.separator)
+	case NumberFormatFixed:
+		return strconv.AppendFloat(buf, v, 'f', nf.precision, 64)
+	case NumberFormatPercent:
+		buf = strconv.AppendFloat(buf, v*100, 'f', nf.precision, 64)
+		return append(buf, '%')
+	case NumberFormatDuration:
+		var d time.Duration
+		if val.Kind() == slog.KindDuration {
+			d = val.Duration()
+		} else {
+			ns := v * float64(nf.unit)
+			// durations end at about 292 years, past
+			// that the number is written as it is
+			if ns >= math.MaxInt64 || ns < math.MinInt64 {
+				return strconv.AppendFloat(buf, v, 'g', -1, 64)
+			}
+			d = time.Duration(ns)
+		}
+		if nf.round > 0 {
+			d = d.Round(nf.round)
+		}
+		return appendDuration(buf, d)
+	default:
+		return strconv.AppendFloat(buf, v, 'g', -1, 64)
+	}
+}
+
+var (
+	siUnits  = []string{"B", "kB", "MB", "GB", "TB", "PB", "EB"}
+	iecUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+)
+
+func appendByteSize(buf []byte, v float64, base float64, units []string, precision int) []byte {
+	abs := math.Abs(v)
+	if abs < base {
+		buf = strconv.AppendFloat(buf, v, 'f', 0, 64)
+		buf = append(buf, ' ')
+		return append(buf, units[0]...)
+	}
+	exp := 0
+	for abs >= base && exp < len(units)-1 {
+		abs /= base
+		v /= base
+		exp++
+	}
+	buf = strconv.AppendFloat(buf, v, 'f', precision, 64)
+	buf = append(buf, ' ')
+	return append(buf, units[exp]...)
+}
+
+func appendThousands(buf []byte, digits []byte, separator string) []byte {
+	if len(digits) > 0 && digits[0] == '-' {
+		buf = append(buf, '-')
+		digits = digits[1:]
+	}
+	intLen := len(digits)
+	if dot := bytes.IndexByte(digits, '.'); dot >= 0 {
+		intLen = dot
+	}
+	for i := 0; i < intLen; i++ {
+		if i > 0 && (intLen-i)%3 == 0 {
+			buf = append(buf, separator...)
+		}
+		buf = append(buf, digits[i])
+	}
+	return append(buf, digits[intLen:]...)
+}
+
+// appendDuration writes d the same as d.String() does, without
+// the string it allocates
+func appendDuration(buf []byte, d time.Duration) []byte {
+	u := uint64(d)
+	if d < 0 {
+		buf = append(buf, '-')
+		u = -u
+	}
+	if u < uint64(time.Second) {
+		switch {
+		case u == 0:
+			return append(buf, "0s"...)
+		case u < uint64(time.Microsecond):
+			buf = strconv.AppendUint(buf, u, 10)
+			return append(buf, "ns"...)
+		case u < uint64(time.Millisecond):
+			buf = appendFraction(buf, u, 3)
+			return append(buf, "\u00b5s"...)
+		}
+		buf = appendFraction(buf, u, 6)
+		return append(buf, "ms"...)
+	}
+	hours := u / uint64(time.Hour)
+	u -= hours * uint64(time.Hour)
+	minutes := u / uint64(time.Minute)
+	u -= minutes * uint64(time.Minute)
+	if hours > 0 {
+		buf = strconv.AppendUint(buf, hours, 10)
+		buf = append(buf, 'h')
+	}
+	if hours > 0 || minutes > 0 {
+		buf = strconv.AppendUint(buf, minutes, 10)
+		buf = append(buf, 'm')
+	}
+	buf = appendFraction(buf, u, 9)
+	return append(buf, 's')
+}
+
+// appendFraction writes v divided by 10^prec,
+// leaving off the trailing zeros of the fraction
+func appendFraction(buf []byte, v uint64, prec int) []byte {
+	unit := uint64(1)
+	for range prec {
+		unit *= 10
+	}
+	buf = strconv.AppendUint(buf, v/unit, 10)
+	frac := v % unit
+	if frac == 0 {
+		return buf
+	}
+	var digits [9]byte
+	for i := prec - 1; i >= 0; i-- {
+		digits[i] = byte('0' + frac%10)
+		frac /= 10
+	}
+	for digits[prec-1] == '0' {
+		prec--
+	}
+	buf = append(buf, '.')
+	return append(buf, digits[:prec]...)
+}
@@ -0,0 +1,110 @@
+package rainbow_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/nerdwave-nick/rainbow"
+)
+
+func TestRainbow_NumberFormats(t *testing.T) {
+	formats := []rainbow.NumberFormat{
+		{Keys: []string{"*_iec"}, Kind: rainbow.NumberFormatBytesIEC},
+		{Keys: []string{"*_si"}, Kind: rainbow.NumberFormatBytesSI, Precision: 2},
+		{Keys: []string{"count"}, Kind: rainbow.NumberFormatThousands},
+		{Keys: []string{"ratio"}, Kind: rainbow.NumberFormatPercent},
+		{Keys: []string{"pi"}, Kind: rainbow.NumberFormatFixed, Precision: 3},
+		{Keys: []string{"latency"}, Kind: rainbow.NumberFormatDuration, Round: time.Millisecond},
+		{Keys: []string{"took_ms"}, Kind: rainbow.NumberFormatDuration, Unit: time.Millisecond},
+		{Keys: []string{"http.*.size"}, Kind: rainbow.NumberFormatBytesIEC, ShowRaw: true},
+	}
+
+	tests := []struct {
+		Attr   slog.Attr
+		Output string
+	}{
+		{Attr: slog.Int("body_iec", 1468006), Output: "body_iec=1.4 MiB"},
+		{Attr: slog.Int("small_iec", 512), Output: "small_iec=512 B"},
+		{Attr: slog.Uint64("body_si", 1500000), Output: "body_si=1.50 MB"},
+		{Attr: slog.Int("count", -1234567), Output: "count=-1,234,567"},
+		{Attr: slog.Float64("count", 1234.5), Output: "count=1,234.5"},
+		{Attr: slog.Float64("ratio", 0.25), Output: "ratio=25.0%"},
+		{Attr: slog.Float64("pi", 3.14159), Output: "pi=3.142"},
+		{Attr: slog.Duration("latency", 1234567890), Output: "latency=1.235s"},
+		{Attr: slog.Int("took_ms", 1500), Output: "took_ms=1.5s"},
+		{Attr: slog.Float64("took_ms", 1e300), Output: "took_ms=1e+300"},
+		{Attr: slog.Float64("took_ms", -1e16), Output: "took_ms=-1e+16"},
+		{Attr: slog.Float64("count", math.Inf(1)), Output: "count=+Inf"},
+		{Attr: slog.Float64("count", math.NaN()), Output: "count=NaN"},
+		{Attr: slog.Float64("body_iec", math.Inf(-1)), Output: "body_iec=-Inf"},
+		{Attr: slog.Float64("body_si", math.NaN()), Output: "body_si=NaN"},
+		{Attr: slog.Float64("ratio", math.Inf(1)), Output: "ratio=+Inf"},
+		{Attr: slog.Float64("pi", math.NaN()), Output: "pi=NaN"},
+		{Attr: slog.Float64("took_ms", math.Inf(-1)), Output: "took_ms=-Inf"},
+		{Attr: slog.Int("size", 2048), Output: "size=2048"},
+		{Attr: slog.Group("http", slog.Group("response", slog.Int("size", 2048))), Output: "http.response.size=2.0 KiB (2048)"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("number format test %d", i), func(t *testing.T) {
+			buffer := bytes.NewBuffer(make([]byte, 0))
+			h := rainbow.New(buffer, &rainbow.Options{NoColor: true, NumberFormats: formats})
+			r := slog.NewRecord(time.Time{}, slog.LevelInfo, "m", 0)
+			r.AddAttrs(tt.Attr)
+			if err := h.Handle(context.Background(), r); err != nil {
+				t.Fatal(err)
+			}
+			expected := "|INF m\n\t" + tt.Output + "\n"
+			if buffer.String() != expected {
+				t.Errorf("output %q did not match the expected output %q", buffer.String(), expected)
+			}
+		})
+	}
+}
+
+func TestRainbow_NegativeColor(t *testing.T) {
+	opts := opts
+	valueOverrides := *opts.ValueOverrides
+	valueOverrides.Negative = "<vn>"
+	opts.ValueOverrides = &valueOverrides
+
+	buffer := bytes.NewBuffer(make([]byte, 0))
+	h := rainbow.New(buffer, &opts)
+	r := slog.NewRecord(time.Time{}, slog.LevelInfo, "m", 0)
+	r.AddAttrs(slog.Int("a", -1), slog.Int("b", 1), slog.Float64("c", -0.5), slog.Duration("d", -time.Second))
+	if err := h.Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	expected := "<li>|INF <ro><m>m<ro><so><mas><ro>" +
+		"<kd>a<ro><so>=<ro><vn>-1<ro><so><aas><ro>" +
+		"<kd>b<ro><so>=<ro><vi>1<ro><so><aas><ro>" +
+		"<kd>c<ro><so>=<ro><vn>-0.5<ro><so><aas><ro>" +
+		"<kd>d<ro><so>=<ro><vn>-1s<ro>\n"
+	if buffer.String() != expected {
+		t.Errorf("output %q did not match the expected output %q", buffer.String(), expected)
+	}
+}
+
+func TestRainbow_DurationValues(t *testing.T) {
+	durations := []time.Duration{
+		0, 1, 999, 1500, 1050, 1005, 999999, 1234567, time.Second, time.Second + 1,
+		61 * time.Second, 90 * time.Minute, time.Hour + 1, -1500, -time.Hour,
+		time.Duration(1<<63 - 1), time.Duration(-1 << 63),
+	}
+	for i, d := range durations {
+		t.Run(fmt.Sprintf("duration test %d", i), func(t *testing.T) {
+			buffer := bytes.NewBuffer(make([]byte, 0))
+			h := rainbow.New(buffer, &rainbow.Options{NoColor: true, Layout: "{attrs}", AttrLayout: rainbow.AttrLayoutSingle})
+			handleNoTime(t, h, slog.LevelInfo, "", slog.Duration("d", d))
+			expected := "d=" + d.String() + "\n"
+			if buffer.String() != expected {
+				t.Errorf("output %q did not match the expected output %q", buffer.String(), expected)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -18,48 +19,82 @@ type TextHandler struct {
 
 	level slog.Leveler
 
-	levelColors   *LevelColorOverrides
-	valueColors   *ValueColorOverrides
-	keyColors     *KeyColorOverrides
-	specialColors *SpecialColorOverrides
+	withColor bool
+	defaults  *overrideStyler
+	styler    Styler
 
 	resetMod  AnsiMod
 	symbolMod AnsiMod
+	// put together in New, they are written for every record
+	symbols       symbols
+	levelPrefixes map[string][4]string
 
 	baseState handleState
 
+	numberFormats []numberFormat
+
+	layout     []layoutField
+	attrLayout AttrLayout
+	// attrs stay where the layout has them, even on multiple lines
+	attrsInPlace bool
+	groupStyle   GroupStyle
+	// terminal width, for AttrLayoutAuto and wrapping,
+	// shared by all clones so it follows resizes
+	width *termWidth
+	wrap  bool
+
+	alignKeys   bool
+	maxKeyWidth int
+
+	columns *columns
+
+	levelStyle LevelStyle
+	levelIcons LevelIcons
+	tint       *LineTint
+
+	messageTemplate MessageTemplate
+	highlight       *highlighter
+	links           *linker
+
+	// shared by all clones, nil without batching
+	batch *batcher
+
 	messageAttrSeparator string
 	attrAttrSeparator    string
 }
 
 type LevelColorOverrides struct {
-	Error   AnsiMod
-	Warning AnsiMod
-	Debug   AnsiMod
-	Info    AnsiMod
+	Error   AnsiMod `json:"error,omitempty"`
+	Warning AnsiMod `json:"warning,omitempty"`
+	Debug   AnsiMod `json:"debug,omitempty"`
+	Info    AnsiMod `json:"info,omitempty"`
 }
 
 type SpecialColorOverrides struct {
-	Time    AnsiMod
-	Message AnsiMod
+	Time    AnsiMod `json:"time,omitempty"`
+	Message AnsiMod `json:"message,omitempty"`
+	Source  AnsiMod `json:"source,omitempty"`
 }
 
 type ValueColorOverrides struct {
-	String   AnsiMod
-	Int      AnsiMod
-	Float    AnsiMod
-	Uint     AnsiMod
-	Error    AnsiMod
-	Time     AnsiMod
-	Bool     AnsiMod
-	Duration AnsiMod
-	Any      AnsiMod
+	String   AnsiMod `json:"string,omitempty"`
+	Int      AnsiMod `json:"int,omitempty"`
+	Float    AnsiMod `json:"float,omitempty"`
+	Uint     AnsiMod `json:"uint,omitempty"`
+	Error    AnsiMod `json:"error,omitempty"`
+	Time     AnsiMod `json:"time,omitempty"`
+	Bool     AnsiMod `json:"bool,omitempty"`
+	Duration AnsiMod `json:"duration,omitempty"`
+	Any      AnsiMod `json:"any,omitempty"`
+	// used instead of Int, Float, Uint or Duration
+	// for values below zero, if set
+	Negative AnsiMod `json:"negative,omitempty"`
 }
 
 type KeyColorOverrides struct {
-	Default  AnsiMod
-	KeyMap   map[string]AnsiMod
-	GroupMap map[string]AnsiMod
+	Default  AnsiMod            `json:"default,omitempty"`
+	KeyMap   map[string]AnsiMod `json:"keyMap,omitempty"`
+	GroupMap map[string]AnsiMod `json:"groupMap,omitempty"`
 }
 
 type Options struct {
@@ -73,16 +108,107 @@ type Options struct {
 	// ignored if NO_COLOR env var is set to ANYTHING
 	NoColor bool
 
+	// separators used by AttrLayoutMulti, both default to "\n\t"
 	MessageAttrSeparator string
 	AttrAttrSeparator    string
 
+	// AttrLayout decides if attrs go on lines of their own, defaults to
+	// AttrLayoutMulti. With the default Layout the message stays on the
+	// first line in every layout, a Layout of its own keeps the attrs
+	// where its {attrs} field is.
+	AttrLayout AttrLayout
+	// GroupStyle decides how groups are shown on multiple lines,
+	// defaults to dotted prefixes. Single lines always use braces.
+	GroupStyle GroupStyle
+	// AlignKeys pads the keys of a record to the same width when attrs
+	// are on lines of their own, so that the values line up.
+	// Group prefixes count as part of the key.
+	AlignKeys bool
+	// MaxKeyWidth leaves longer keys out of the alignment, so a single
+	// long key doesn't push all values away. Defaults to 24.
+	MaxKeyWidth int
+	// Columns lines up single line records with the records before
+	// them, nil turns it off. Attrs on lines of their own are left as they are.
+	Columns *ColumnOptions
+	// Wrap breaks lines wider than Width, at spaces where possible.
+	// The lines that get added are indented to where the message
+	// or the value started.
+	Wrap bool
+	// Width of the terminal for AttrLayoutAuto and Wrap. If zero it is
+	// found out from the writer, following resizes of a terminal,
+	// or $COLUMNS.
+	Width int
+
+	// LevelStyle sets the level apart as a block of color,
+	// defaults to LevelStyleText. Without color it is always text.
+	LevelStyle LevelStyle
+	// LevelIcons are written in front of the level name. Emoji and
+	// Nerd Font icons fall back to ASCII ones when the terminal
+	// doesn't look like it can show them.
+	LevelIcons LevelIcons
+	// LineTint colors the whole line of records at or above its level
+	// in the level color, nil turns it off
+	LineTint *LineTint
+	// MessageTemplate fills in placeholders like "user {user} logged in"
+	// with the values of the attrs, {group.key} for attrs in groups.
+	// Keys without an attr are shown in the warning color.
+	MessageTemplate MessageTemplate
+	// Highlight colors numbers, quoted text, URLs, IPs, UUIDs, paths,
+	// durations and HTTP methods inside of messages and string values,
+	// in the colors of HighlightOverrides
+	Highlight bool
+	// Hyperlinks makes URLs and absolute paths in messages and string
+	// values, the source and the values of LinkRules clickable. It needs
+	// color, and a terminal known to support OSC 8 links, see
+	// FORCE_HYPERLINK to say it does.
+	Hyperlinks bool
+	// LinkRules are tried in order, the first one with
+	// a matching key decides the link of a value
+	LinkRules []LinkRule
+	// Batch collects records into larger writes, nil writes every
+	// record on its own. Call Flush before exiting with it on.
+	Batch *BatchOptions
+
 	LevelOverrides   *LevelColorOverrides
 	ValueOverrides   *ValueColorOverrides
 	KeyOverrides     *KeyColorOverrides
 	SpecialOverrides *SpecialColorOverrides
+	// HighlightOverrides are only used with Highlight on
+	HighlightOverrides *HighlightColorOverrides
 
 	SymbolOverride AnsiMod
 	ResetOverride  AnsiMod
+
+	// NumberFormats are tried in order, the first one with
+	// a matching key decides how a number is printed
+	NumberFormats []NumberFormat
+	// ColorRules are tried in order, the first one matching
+	// both key and value overrides the value color
+	ColorRules []ColorRule
+	// HashColors enables colors derived from hashing keys and values,
+	// nil turns them off
+	HashColors *HashColorOptions
+
+	// Layout is a template for the fields of a line, like
+	// "{level} {time:15:04:05} {source} {msg,-40} {attrs}".
+	// Fields are written as {name[,width][:format]}:
+	//   - time, formatted with a time layout
+	//   - level, as short (INF), long (INFO), char (I) or bar (|INF )
+	//   - msg
+	//   - source, the short dir/file.go:line or long with the full path
+	//   - attrs
+	// A width pads the field to that many columns, right aligned,
+	// left aligned for -width and centered for ^width. Text around the
+	// fields is written in the symbol color, {{ and }} for braces.
+	// Fields that are left out of the layout aren't written, text right
+	// after a field without a value (no time, no attrs) is left out too,
+	// and so is text glued to its front, like the brackets of " [{source}]".
+	// Defaults to DefaultLayout, see ValidateLayout.
+	Layout string
+
+	// Styler is layered on top of the styles from the overrides above,
+	// its styles are merged over the defaults, see Compose and DefaultStyler.
+	Styler Styler
 }
 
 func (h *TextHandler) clone() *TextHandler {
@@ -92,16 +218,42 @@ func (h *TextHandler) clone() *TextHandler {
 
 		level: h.level,
 
-		levelColors:   h.levelColors,
-		valueColors:   h.valueColors,
-		keyColors:     h.keyColors,
-		specialColors: h.specialColors,
+		withColor: h.withColor,
+		defaults:  h.defaults,
+		styler:    h.styler,
 
 		resetMod:  h.resetMod,
 		symbolMod: h.symbolMod,
 
+		symbols:       h.symbols,
+		levelPrefixes: h.levelPrefixes,
+
 		baseState: *h.baseState.clone(),
 
+		numberFormats: h.numberFormats,
+
+		layout:       h.layout,
+		attrLayout:   h.attrLayout,
+		attrsInPlace: h.attrsInPlace,
+		groupStyle:   h.groupStyle,
+		width:        h.width,
+		wrap:         h.wrap,
+
+		alignKeys:   h.alignKeys,
+		maxKeyWidth: h.maxKeyWidth,
+
+		columns: h.columns,
+
+		levelStyle: h.levelStyle,
+		levelIcons: h.levelIcons,
+		tint:       h.tint,
+
+		messageTemplate: h.messageTemplate,
+		highlight:       h.highlight,
+		links:           h.links,
+
+		batch: h.batch,
+
 		attrAttrSeparator:    h.attrAttrSeparator,
 		messageAttrSeparator: h.messageAttrSeparator,
 	}
@@ -120,10 +272,6 @@ func New(out io.Writer, opts *Options) slog.Handler {
 	if opts.Level == nil {
 		opts.Level = slog.LevelInfo
 	}
-	levelColors := getOrDefaultLevelColorOverrides(opts.LevelOverrides)
-	valueColors := getOrDefaultValueColorOverrides(opts.ValueOverrides)
-	keyColors := getOrDefaultKeyColorOverrides(opts.KeyOverrides)
-	specialColors := getOrDefaultSpecialOverrides(opts.SpecialOverrides)
 	resetMod := Mod(Fmt.Reset)
 	if opts.ResetOverride != "" {
 		resetMod = opts.ResetOverride
@@ -135,13 +283,6 @@ func New(out io.Writer, opts *Options) slog.Handler {
 	withColor := !opts.NoColor && os.Getenv("NO_COLOR") == ""
 
 	if !withColor {
-		levelColors = &LevelColorOverrides{}
-		valueColors = &ValueColorOverrides{}
-		specialColors = &SpecialColorOverrides{}
-		keyColors = &KeyColorOverrides{
-			GroupMap: make(map[string]AnsiMod),
-			KeyMap:   make(map[string]AnsiMod),
-		}
 		resetMod = ""
 		symbolMod = ""
 	}
@@ -156,20 +297,50 @@ func New(out io.Writer, opts *Options) slog.Handler {
 		attrAttrSeparator = opts.AttrAttrSeparator
 	}
 
+	// broken fields are written as they are
+	layout, _ := compileLayout(opts.Layout)
+
+	maxKeyWidth := defaultMaxKeyWidth
+	if opts.MaxKeyWidth > 0 {
+		maxKeyWidth = opts.MaxKeyWidth
+	}
+
+	lock := &sync.Mutex{}
 	h := &TextHandler{
-		out:           out,
-		lock:          &sync.Mutex{},
-		level:         opts.Level,
-		levelColors:   levelColors,
-		valueColors:   valueColors,
-		keyColors:     keyColors,
-		specialColors: specialColors,
-		resetMod:      resetMod,
-		symbolMod:     symbolMod,
+		out:       out,
+		lock:      lock,
+		level:     opts.Level,
+		withColor: withColor,
+		defaults:  newOverrideStyler(opts),
+		styler:    opts.Styler,
+		resetMod:  resetMod,
+		symbolMod: symbolMod,
+
+		numberFormats: compileNumberFormats(opts.NumberFormats),
+		layout:        layout,
+		attrLayout:    opts.AttrLayout,
+		attrsInPlace:  opts.Layout != "",
+		groupStyle:    opts.GroupStyle,
+		alignKeys:     opts.AlignKeys,
+		maxKeyWidth:   maxKeyWidth,
+		columns:       compileColumns(opts.Columns),
+		width:         newWidth(out, opts),
+		wrap:          opts.Wrap,
+		levelStyle:    opts.LevelStyle,
+		levelIcons:    fallbackIcons(opts.LevelIcons),
+		tint:          opts.LineTint,
+
+		messageTemplate: opts.MessageTemplate,
+		highlight:       compileHighlighter(opts.Highlight, opts.HighlightOverrides),
+		links:           compileLinks(opts.Hyperlinks, withColor, opts.LinkRules),
+
+		batch: compileBatch(opts.Batch, out, lock),
 
 		messageAttrSeparator: messageAttrSeparator,
 		attrAttrSeparator:    attrAttrSeparator,
 	}
+	h.symbols = h.newSymbols()
+	h.levelPrefixes = h.renderLevelPrefixes()
 
 	return h
 }
@@ -210,6 +381,7 @@ func getOrDefaultSpecialOverrides(specialColorOverrides *SpecialColorOverrides)
 	return &SpecialColorOverrides{
 		Time:    Mod(Fmt.Faint, Fg.HiBlack),
 		Message: Mod(),
+		Source:  Mod(Fmt.Faint, Fg.HiBlack),
 	}
 }
 
@@ -232,15 +404,78 @@ func (h *TextHandler) Enabled(_ context.Context, level slog.Level) bool {
 }
 
 type handleState struct {
-	CurrentGroupName       string
-	PreformattedAttributes string
+	CurrentGroupName string
+	// plain group names, for matching keys against group paths
+	Groups []string
+	// colors time and level instead of their usual colors
+	Tint Style
+	// attrs added with WithAttrs, one for every call
+	Context []*contextAttrs
+	// the context is written again instead of the written form
+	// it keeps, as the record changes its styles or attrs
+	Restyle bool
+	// level of the record being written
+	Level slog.Level
+	// attrs go on the same line, see AttrLayoutSingle
+	Inline bool
+	// keys are padded to this width, 0 for no padding
+	KeyWidth int
+	// widths of the message and level columns, 0 for no padding
+	MessageWidth int
+	LevelWidth   int
+	// where the message starts in the buffer, for wrapping
+	MessageStart int
+	// the pinned attrs, written and padded, and where they came from
+	Pinned     []string
+	PinnedRefs []attrRef
+}
+
+// contextAttrs are the attrs of a WithAttrs call. They never change
+// once made, so the handlers made from each other share them, and so
+// do the outputs of a Multi. The ways they are written are kept for
+// each handler, done when they are first needed.
+type contextAttrs struct {
+	Groups []string
+	Attrs  []slog.Attr
+	// writtenKey to string
+	written sync.Map
+}
+
+// writtenKey tells apart the ways context attrs are written
+type writtenKey struct {
+	// the lock is made in New and shared by the clones, so it stands
+	// for the options, like the colors and layout, of the handler
+	lock   *sync.Mutex
+	inline bool
+	level  slog.Level
+}
+
+// newContextAttrs resolves the attrs of a WithAttrs call,
+// nil if there are none left to write
+func newContextAttrs(groups []string, attrs []slog.Attr) *contextAttrs {
+	resolved := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		attr.Value = attr.Value.Resolve()
+		// left out here, so a context is never written empty
+		if attr.Equal(slog.Attr{}) || attr.Value.Kind() == slog.KindGroup && len(attr.Value.Group()) == 0 {
+			continue
+		}
+		resolved = append(resolved, attr)
+	}
+	if len(resolved) == 0 {
+		return nil
+	}
+	return &contextAttrs{Groups: groups, Attrs: resolved}
 }
 
 func (hs *handleState) clone() *handleState {
-	hsc := &handleState{}
-	hsc.CurrentGroupName = strings.Clone(hs.CurrentGroupName)
-	hsc.PreformattedAttributes = strings.Clone(hs.PreformattedAttributes)
-	return hsc
+	// small enough to be inlined, so clones that
+	// don't outlive the caller stay on the stack
+	hsc := *hs
+	// never appended to in place, so sharing the backing array is fine
+	hsc.Groups = hs.Groups[:len(hs.Groups):len(hs.Groups)]
+	hsc.Context = hs.Context[:len(hs.Context):len(hs.Context)]
+	return &hsc
 }
 
 func (h *TextHandler) Handle(ctx context.Context, r slog.Record) error {
@@ -253,39 +488,70 @@ func (h *TextHandler) Handle(ctx context.Context, r slog.Record) error {
 		freeBuf(bufp)
 	}()
 
-	hs := h.baseState.clone()
+	buf = h.appendRecord(buf, r)
+	return h.write(buf, r.Level)
+}
 
-	if !r.Time.IsZero() {
-		buf = h.appendRecordTime(buf, r.Time.Round(0))
+// appendRecord formats a record as it is written, newline included.
+// Only the shared column widths are locked while doing so.
+func (h *TextHandler) appendRecord(buf []byte, r slog.Record) []byte {
+	hs := h.baseState.clone()
+	hs.Level = r.Level
+	if h.styler != nil {
+		// custom stylers may style by level, so the context
+		// has to be styled again for every record
+		hs.CurrentGroupName = h.groupPrefix(hs.Groups, hs)
+		hs.Restyle = true
 	}
-	buf = h.appendRecordLevel(buf, r.Level, hs)
-	buf = fmt.Appendf(buf, "%s%s%s", h.specialColors.Message, r.Message, h.resetMod)
-	if hs.PreformattedAttributes != "" || r.NumAttrs() > 0 {
-		buf = fmt.Appendf(buf, "%s%s%s", h.symbolMod, h.messageAttrSeparator, h.resetMod)
+	if hs.Tint.IsZero() {
+		r.Attrs(func(a slog.Attr) bool {
+			tint, ok := h.tintColor(hs.Groups, a)
+			hs.Tint = tint
+			return !ok
+		})
 	}
-	if hs.PreformattedAttributes != "" {
-		buf = append(buf, hs.PreformattedAttributes...)
-		if r.NumAttrs() > 0 {
-			buf = fmt.Appendf(buf, "%s%s%s", h.symbolMod, h.attrAttrSeparator, h.resetMod)
-		}
+	if h.messageTemplate != MessageTemplateOff && strings.ContainsAny(r.Message, "{}") {
+		r = h.applyTemplate(r, hs)
 	}
 
-	numAttrs := r.NumAttrs()
-	curAttrs := 0
-	r.Attrs(func(a slog.Attr) bool {
-		curAttrs++
-		a.Value = a.Value.Resolve()
-		buf = h.appendAttr(buf, a, hs)
-		if curAttrs < numAttrs {
-			buf = fmt.Appendf(buf, "%s%s%s", h.symbolMod, h.attrAttrSeparator, h.resetMod)
+	switch h.attrLayout {
+	case AttrLayoutSingle, AttrLayoutAuto:
+		hs.Inline = true
+		if h.columns != nil {
+			h.fillColumns(r, hs)
 		}
-		return true
-	})
-	buf = append(buf, '\n')
+		buf = h.appendLayout(buf, r, hs)
+		if h.attrLayout == AttrLayoutAuto && maxLineWidth(buf) > h.width.Load() {
+			hs.Inline = false
+			hs.MessageWidth, hs.LevelWidth = 0, 0
+			buf = h.appendLayout(buf[:0], r, hs)
+		}
+	default:
+		buf = h.appendLayout(buf, r, hs)
+	}
+	if h.wrap {
+		wrapped := allocBuf()
+		*wrapped = h.appendWrapped(*wrapped, buf, hs)
+		buf, *wrapped = *wrapped, buf
+		freeBuf(wrapped)
+	}
+	if tint := h.lineTint(hs); tint != nil {
+		tinted := allocBuf()
+		*tinted = h.appendTinted(*tinted, buf, tint)
+		buf, *tinted = *tinted, buf
+		freeBuf(tinted)
+	}
+	return append(buf, '\n')
+}
 
+// write writes formatted records to out, or to the batch
+func (h *TextHandler) write(b []byte, level slog.Level) error {
 	h.lock.Lock()
 	defer h.lock.Unlock()
-	_, err := h.out.Write(buf)
+	if h.batch != nil {
+		return h.batch.write(b, level)
+	}
+	_, err := h.out.Write(b)
 	return err
 }
 
@@ -293,29 +559,26 @@ func (h *TextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	if len(attrs) == 0 {
 		return h
 	}
-	h2 := h.clone()
+	return h.withContext(h.newContext(attrs))
+}
 
-	bufp := allocBuf()
-	buf := *bufp
-	defer func() {
-		*bufp = buf
-		freeBuf(bufp)
-	}()
+func (h *TextHandler) newContext(attrs []slog.Attr) *contextAttrs {
+	return newContextAttrs(h.baseState.Groups, attrs)
+}
 
-	separator := ""
-	if h2.baseState.PreformattedAttributes != "" {
-		separator = fmt.Sprintf("%s%s%s", h.symbolMod, h.attrAttrSeparator, h.resetMod)
+func (h *TextHandler) withContext(ca *contextAttrs) slog.Handler {
+	if ca == nil {
+		return h
 	}
-	// write attributes to buffer
-	length := len(attrs)
-	for i, attr := range attrs {
-		attr.Value = attr.Value.Resolve()
-		buf = h2.appendAttr(buf, attr, &h2.baseState)
-		if i < length-1 {
-			buf = fmt.Appendf(buf, "%s%s%s", h.symbolMod, h.attrAttrSeparator, h.resetMod)
+	h2 := h.clone()
+	for _, attr := range ca.Attrs {
+		if !h2.baseState.Tint.IsZero() {
+			break
 		}
+		h2.baseState.Tint, _ = h2.tintColor(h2.baseState.Groups, attr)
 	}
-	h2.baseState.PreformattedAttributes = h2.baseState.PreformattedAttributes + separator + string(buf)
+	// the clone can't append to the context of h in place
+	h2.baseState.Context = append(h2.baseState.Context, ca)
 	return h2
 }
 
@@ -325,29 +588,129 @@ func (h *TextHandler) WithGroup(name string) slog.Handler {
 	}
 
 	h2 := h.clone()
-	h2.baseState.CurrentGroupName = h.appendCurrentGroupName(h2.baseState.CurrentGroupName, name)
+	h2.baseState.Level = h.level.Level()
+	h2.baseState.CurrentGroupName = h.appendCurrentGroupName(h2.baseState.CurrentGroupName, name, &h2.baseState)
+	h2.baseState.Groups = append(h2.baseState.Groups, name)
 	return h2
 }
 
-func (h *TextHandler) appendRecordTime(buf []byte, time time.Time) []byte {
-	formattedTime := time.Format("2006-01-02T15:04:05.000")
-	buf = fmt.Appendf(buf, "%s%s%s", h.specialColors.Time, formattedTime, h.resetMod)
-	return buf
+func (h *TextHandler) appendRecordTime(buf []byte, time time.Time, format string, hs *handleState) []byte {
+	col := h.style(StyleRequest{Element: ElementTime, Value: slog.TimeValue(time), Level: hs.Level}, hs)
+	buf = col.AppendSGR(buf)
+	buf = time.AppendFormat(buf, format)
+	return append(buf, h.resetMod...)
 }
 
-func (h *TextHandler) appendRecordLevel(buf []byte, level slog.Level, _ *handleState) []byte {
+func (h *TextHandler) appendRecordLevel(buf []byte, level slog.Level, format string, hs *handleState) []byte {
+	if prefixes, ok := h.levelPrefixes[format]; ok && hs.Tint.IsZero() {
+		if i := levelIndex(level); i >= 0 {
+			return append(buf, prefixes[i]...)
+		}
+	}
+	return h.renderRecordLevel(buf, level, format, hs)
+}
+
+// levelIndex is the index of one of the four levels, -1 for the ones in between
+func levelIndex(level slog.Level) int {
 	switch level {
 	case slog.LevelDebug:
-		return fmt.Appendf(buf, "%s|DBG %s", h.levelColors.Debug, h.resetMod)
+		return 0
 	case slog.LevelInfo:
-		return fmt.Appendf(buf, "%s|INF %s", h.levelColors.Info, h.resetMod)
+		return 1
 	case slog.LevelWarn:
-		return fmt.Appendf(buf, "%s|WRN %s", h.levelColors.Warning, h.resetMod)
+		return 2
 	case slog.LevelError:
-		return fmt.Appendf(buf, "%s|ERR %s", h.levelColors.Error, h.resetMod)
-	default:
-		return fmt.Appendf(buf, "|INVALID ")
+		return 3
+	}
+	return -1
+}
+
+// renderLevelPrefixes writes the four levels ahead of time, for every
+// level field of the layout. A custom styler can style them differently
+// every time, so they are left to be written with the record then.
+func (h *TextHandler) renderLevelPrefixes() map[string][4]string {
+	if h.styler != nil {
+		return nil
+	}
+	prefixes := map[string][4]string{}
+	for _, f := range h.layout {
+		if f.kind != layoutLevel {
+			continue
+		}
+		var p [4]string
+		for i, level := range []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError} {
+			p[i] = string(h.renderRecordLevel(nil, level, f.text, &handleState{Level: level}))
+		}
+		prefixes[f.text] = p
+	}
+	return prefixes
+}
+
+func (h *TextHandler) renderRecordLevel(buf []byte, level slog.Level, format string, hs *handleState) []byte {
+	name, ok := levelText(level, format)
+	if !ok {
+		return append(buf, name...)
+	}
+	col := h.style(StyleRequest{Element: ElementLevel, Level: level}, hs)
+	bar := format == "bar"
+	if bar {
+		name = strings.TrimSuffix(strings.TrimPrefix(name, "|"), " ")
 	}
+	if icon := h.levelIcon(level); icon != "" {
+		name = icon + " " + name
+	}
+	switch {
+	case h.levelStyle != LevelStyleText && h.withColor:
+		// the bar is replaced by the block
+		buf = h.badgeStyle(col).AppendSGR(buf)
+		buf = append(append(append(buf, ' '), name...), ' ')
+		buf = append(buf, h.resetMod...)
+		if bar {
+			buf = append(buf, ' ')
+		}
+		return buf
+	case bar:
+		buf = col.AppendSGR(buf)
+		buf = append(append(append(buf, '|'), name...), ' ')
+		return append(buf, h.resetMod...)
+	}
+	return h.appendStyled(buf, col, name)
+}
+
+// levelText names a level in one of the layout formats,
+// false for levels the bar format has no name for
+func levelText(level slog.Level, format string) (string, bool) {
+	if format == "bar" {
+		switch level {
+		case slog.LevelDebug:
+			return "|DBG ", true
+		case slog.LevelInfo:
+			return "|INF ", true
+		case slog.LevelWarn:
+			return "|WRN ", true
+		case slog.LevelError:
+			return "|ERR ", true
+		default:
+			return "|INVALID ", false
+		}
+	}
+	name := level.String()
+	switch format {
+	case "", "short":
+		switch level {
+		case slog.LevelDebug:
+			name = "DBG"
+		case slog.LevelInfo:
+			name = "INF"
+		case slog.LevelWarn:
+			name = "WRN"
+		case slog.LevelError:
+			name = "ERR"
+		}
+	case "char":
+		name = name[:1]
+	}
+	return name, true
 }
 
 func (h *TextHandler) appendAttr(buf []byte, a slog.Attr, hs *handleState) []byte {
@@ -358,64 +721,251 @@ func (h *TextHandler) appendAttr(buf []byte, a slog.Attr, hs *handleState) []byt
 	kind := a.Value.Kind()
 
 	if kind != slog.KindGroup {
-		keyCol, ok := h.keyColors.KeyMap[a.Key]
-		if !ok {
-			keyCol = h.keyColors.Default
+		keyCol := h.style(StyleRequest{Element: ElementKey, Key: a.Key, Groups: hs.Groups, Value: a.Value, Level: hs.Level}, hs)
+		buf = append(buf, hs.CurrentGroupName...)
+		buf = h.appendStyled(buf, keyCol, a.Key)
+		buf = h.appendKeyPad(buf, a.Key, hs)
+		buf = append(buf, h.symbols.equals...)
+		return h.appendValue(buf, a, hs)
+	}
+
+	attrs := a.Value.Group()
+	grLen := len(attrs)
+	// Ignore empty groups.
+	if grLen == 0 {
+		return buf
+	}
+	hss := hs.clone()
+	if hs.Inline {
+		// braces keep the nesting visible on a single line
+		grCol := h.style(StyleRequest{Element: ElementGroup, Key: a.Key, Groups: hs.Groups, Level: hs.Level}, hs)
+		buf = append(buf, hs.CurrentGroupName...)
+		buf = h.appendStyled(buf, grCol, a.Key)
+		buf = append(buf, h.symbols.openGroup...)
+		hss.CurrentGroupName = ""
+		hss.Groups = append(hss.Groups, a.Key)
+		buf = h.appendAttrs(buf, attrs, hss)
+		return append(buf, h.symbols.closeGroup...)
+	}
+	hss.CurrentGroupName = h.appendCurrentGroupName(hss.CurrentGroupName, a.Key, hss)
+	hss.Groups = append(hss.Groups, a.Key)
+	return h.appendAttrs(buf, attrs, hss)
+}
+
+// appendValue writes the value of an attr that isn't a group
+func (h *TextHandler) appendValue(buf []byte, a slog.Attr, hs *handleState) []byte {
+	if target := h.links.ruleTarget(hs.Groups, a); target != "" {
+		buf = appendLinkStart(buf, target)
+		buf = h.appendValueText(buf, a, hs, false)
+		return appendLinkEnd(buf)
+	}
+	return h.appendValueText(buf, a, hs, true)
+}
+
+// appendValueText writes a value, with links inside of strings if link is set
+func (h *TextHandler) appendValueText(buf []byte, a slog.Attr, hs *handleState, link bool) []byte {
+	kind := a.Value.Kind()
+	valCol := h.style(StyleRequest{Element: ElementValue, Key: a.Key, Groups: hs.Groups, Value: a.Value, Level: hs.Level}, hs)
+	if isNumberKind(kind) {
+		if nf := h.numberFormatFor(hs.Groups, a.Key); nf != nil {
+			return h.appendFormattedNumber(buf, a.Value, nf, valCol)
 		}
-		buf = fmt.Appendf(buf, "%s%s%s%s%s=%s", hs.CurrentGroupName, keyCol, a.Key, h.resetMod, h.symbolMod, h.resetMod)
 	}
-	switch a.Value.Kind() {
+	if kind == slog.KindGroup || kind == slog.KindLogValuer {
+		// not resolved, written the way slog does
+		return fmt.Append(buf, a.Value)
+	}
+	buf = valCol.AppendSGR(buf)
+	switch kind {
 	case slog.KindInt64:
-		buf = fmt.Appendf(buf, "%s%d%s", h.valueColors.Int, a.Value.Int64(), h.resetMod)
+		buf = strconv.AppendInt(buf, a.Value.Int64(), 10)
 	case slog.KindFloat64:
-		buf = fmt.Appendf(buf, "%s%g%s", h.valueColors.Float, a.Value.Float64(), h.resetMod)
+		buf = strconv.AppendFloat(buf, a.Value.Float64(), 'g', -1, 64)
 	case slog.KindUint64:
-		buf = fmt.Appendf(buf, "%s%d%s", h.valueColors.Uint, a.Value.Uint64(), h.resetMod)
+		buf = strconv.AppendUint(buf, a.Value.Uint64(), 10)
 	case slog.KindString:
-		buf = fmt.Appendf(buf, "%s%q%s", h.valueColors.String, a.Value.String(), h.resetMod)
+		if h.highlight != nil || h.links != nil {
+			quoted := a.Value.String()
+			if needsEscape(quoted) {
+				quoted = strconv.Quote(quoted)
+				quoted = quoted[1 : len(quoted)-1]
+			}
+			buf = h.appendHighlighted(append(buf, '"'), quoted, valCol, link)
+			buf = append(buf, '"')
+			break
+		}
+		buf = strconv.AppendQuote(buf, a.Value.String())
 	case slog.KindBool:
-		buf = fmt.Appendf(buf, "%s%t%s", h.valueColors.Bool, a.Value.Bool(), h.resetMod)
+		buf = strconv.AppendBool(buf, a.Value.Bool())
 	case slog.KindTime:
 		// Write times in a standard way
-		formattedTime := a.Value.Time().Format("2006-01-02T15:04:05.000")
-		buf = fmt.Appendf(buf, "%s%s%s", h.valueColors.Time, formattedTime, h.resetMod)
+		buf = a.Value.Time().AppendFormat(buf, "2006-01-02T15:04:05.000")
 	case slog.KindDuration:
-		formattedDuration := a.Value.Duration().String()
-		buf = fmt.Appendf(buf, "%s%s%s", h.valueColors.Duration, formattedDuration, h.resetMod)
-	case slog.KindGroup:
-		attrs := a.Value.Group()
-		grLen := len(attrs)
-		// Ignore empty groups.
-		if grLen == 0 {
-			return buf
-		}
-		hss := hs.clone()
-		hss.CurrentGroupName = h.appendCurrentGroupName(hss.CurrentGroupName, a.Key)
-		for i, ga := range attrs {
-			buf = h.appendAttr(buf, ga, hss)
-			if i < grLen-1 {
-				buf = fmt.Appendf(buf, "%s%s%s", h.symbolMod, h.attrAttrSeparator, h.resetMod)
-			}
-		}
+		buf = appendDuration(buf, a.Value.Duration())
 	case slog.KindAny:
-		errVal, ok := a.Value.Any().(error)
-		if ok {
-			buf = fmt.Appendf(buf, "%s%s%s", h.valueColors.Error, errVal.Error(), h.resetMod)
+		if err, ok := a.Value.Any().(error); ok {
+			buf = append(buf, err.Error()...)
 		} else {
-			buf = fmt.Appendf(buf, "%s%v%s", h.valueColors.Any, a.Value.Any(), h.resetMod)
+			buf = fmt.Append(buf, a.Value.Any())
 		}
+	}
+	return append(buf, h.resetMod...)
+}
+
+func isNumberKind(kind slog.Kind) bool {
+	switch kind {
+	case slog.KindInt64, slog.KindUint64, slog.KindFloat64, slog.KindDuration:
+		return true
 	default:
-		buf = fmt.Appendf(buf, "%v", a.Value)
+		return false
+	}
+}
+
+func (h *TextHandler) appendFormattedNumber(buf []byte, v slog.Value, nf *numberFormat, col Style) []byte {
+	buf = col.AppendSGR(buf)
+	buf = nf.appendValue(buf, v)
+	buf = append(buf, h.resetMod...)
+	if nf.showRaw {
+		buf = append(buf, h.symbolMod...)
+		buf = append(buf, " ("...)
+		buf = fmt.Append(buf, v)
+		buf = append(buf, ')')
+		buf = append(buf, h.resetMod...)
 	}
 	return buf
 }
 
-func (h *TextHandler) appendCurrentGroupName(currentGroupName, newGroupName string) string {
-	col, ok := h.keyColors.GroupMap[newGroupName]
-	if !ok {
-		col = h.keyColors.Default
+// symbols are the separators in the symbol color
+type symbols struct {
+	attrSeparator    string
+	messageSeparator string
+	// separates both attrs and the message on single lines
+	space      string
+	equals     string
+	dot        string
+	openGroup  string
+	closeGroup string
+}
+
+func (h *TextHandler) newSymbols() symbols {
+	symbol := func(text string) string {
+		return string(h.symbolMod) + text + string(h.resetMod)
+	}
+	return symbols{
+		attrSeparator:    symbol(h.attrAttrSeparator),
+		messageSeparator: symbol(h.messageAttrSeparator),
+		space:            symbol(" "),
+		equals:           symbol("="),
+		dot:              symbol("."),
+		openGroup:        symbol("={"),
+		closeGroup:       symbol("}"),
+	}
+}
+
+// attrSeparator goes between attrs, a space on single lines
+func (h *TextHandler) attrSeparator(hs *handleState) string {
+	if hs.Inline {
+		return h.symbols.space
+	}
+	return h.symbols.attrSeparator
+}
+
+// messageSeparator goes between the line and the attrs
+func (h *TextHandler) messageSeparator(hs *handleState) string {
+	if hs.Inline {
+		return h.symbols.space
+	}
+	return h.symbols.messageSeparator
+}
+
+// appendStyled writes text in st, followed by a reset
+func (h *TextHandler) appendStyled(buf []byte, st Style, text string) []byte {
+	buf = st.AppendSGR(buf)
+	buf = append(buf, text...)
+	return append(buf, h.resetMod...)
+}
+
+// appendAttrs writes attributes separated by the attr separator
+func (h *TextHandler) appendAttrs(buf []byte, attrs []slog.Attr, hs *handleState) []byte {
+	for i, a := range attrs {
+		buf = h.appendAttr(buf, a, hs)
+		if i < len(attrs)-1 {
+			buf = append(buf, h.attrSeparator(hs)...)
+		}
+	}
+	return buf
+}
+
+func (h *TextHandler) appendCurrentGroupName(currentGroupName, newGroupName string, hs *handleState) string {
+	col := h.style(StyleRequest{Element: ElementGroup, Key: newGroupName, Groups: hs.Groups, Level: hs.Level}, hs)
+	buf := make([]byte, 0, len(currentGroupName)+len(newGroupName)+64)
+	buf = append(buf, currentGroupName...)
+	buf = h.appendStyled(buf, col, newGroupName)
+	buf = append(buf, h.symbols.dot...)
+	return string(buf)
+}
+
+// groupPrefix styles the dotted prefix for the given groups
+func (h *TextHandler) groupPrefix(groups []string, hs *handleState) string {
+	prefix := ""
+	hss := hs.clone()
+	for i, name := range groups {
+		hss.Groups = groups[:i:i]
+		prefix = h.appendCurrentGroupName(prefix, name, hss)
+	}
+	return prefix
+}
+
+// appendContext writes the WithAttrs attributes, in the form they
+// were written in before unless they have to be written again for
+// the record, padded to its keys or styled for its level
+func (h *TextHandler) appendContext(buf []byte, hs *handleState) []byte {
+	restyle := hs.Restyle || hs.KeyWidth > 0
+	for i, ca := range hs.Context {
+		if i > 0 {
+			buf = append(buf, h.attrSeparator(hs)...)
+		}
+		if !restyle {
+			buf = append(buf, h.writtenContext(ca, hs.Inline)...)
+			continue
+		}
+		hss := hs.clone()
+		hss.Groups = ca.Groups
+		hss.CurrentGroupName = h.groupPrefix(ca.Groups, hs)
+		buf = h.appendAttrs(buf, ca.Attrs, hss)
+	}
+	return buf
+}
+
+// writtenContext is the written form of context attrs, written the first
+// time it's needed. They are styled with the minimum level, and written
+// again when that changes, custom stylers restyle them for every record
+// anyway.
+func (h *TextHandler) writtenContext(ca *contextAttrs, inline bool) string {
+	key := writtenKey{lock: h.lock, inline: inline, level: h.level.Level()}
+	if s, ok := ca.written.Load(key); ok {
+		return s.(string)
+	}
+	hs := &handleState{Groups: ca.Groups, Level: key.level, Inline: inline}
+	hs.CurrentGroupName = h.groupPrefix(ca.Groups, hs)
+	bufp := allocBuf()
+	*bufp = h.appendAttrs(*bufp, ca.Attrs, hs)
+	s := string(*bufp)
+	freeBuf(bufp)
+	// handlers writing it at the same time all come up with the same
+	ca.written.Store(key, s)
+	return s
+}
+
+// needsEscape tells if quoting s changes more than the quotes around
+// it, anything outside of printable ASCII is left to strconv
+func needsEscape(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c >= 0x7f || c == '"' || c == '\\' {
+			return true
+		}
 	}
-	return fmt.Sprintf("%s%s%s%s%s.%s", currentGroupName, col, newGroupName, h.resetMod, h.symbolMod, h.resetMod)
+	return false
 }
 
 // see https://github.com/golang/example/blob/master/slog-handler-guide/README.md#speed
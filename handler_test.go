@@ -3,13 +3,17 @@ package rainbow_test
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"regexp"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/nerdwave-nick/rainbow"
+	"github.com/nerdwave-nick/rainbow/ansi"
 )
 
 func attrRE(attr *slog.Attr, opts *rainbow.Options, pgr string) string {
@@ -492,3 +496,157 @@ func TestRainbow_HandlerWithGroupManualNoColor(t *testing.T) {
 
 // ERR Testing Attributes<mas>wg.some=\"attribute\"<aas>wg.i64k=23<aas>wg.ik=23<aas>wg.bk=true<aas>wg.fk=324.2<aas>wg.dk=12s<aas>wg.gr.tk=1970-01-01T01:00:01.001<aas>wg.gr.err=err<aas>wg.gr.rk={1 2}\n
 // ERR Testing Attributes<mas>wg.some=\"attribute\"<aas>wg.i64k=23<aas>wg.ik=23<aas>wg.bk=true<aas>wg.fk=324.2<aas>wg.dk=12s<aas>wg.gr.tk=1970-01-01T01:00:01.001wg.gr.err=errwg.gr.rk={1 2}\n
+
+// handleNoTime handles a record without a time, to get output that's easy to compare
+func handleNoTime(t *testing.T, h slog.Handler, level slog.Level, msg string, attrs ...slog.Attr) {
+	t.Helper()
+	r := slog.NewRecord(time.Time{}, level, msg, 0)
+	r.AddAttrs(attrs...)
+	if err := h.Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRainbow_HandlerStripped(t *testing.T) {
+	colored := bytes.NewBuffer(make([]byte, 0))
+	plain := bytes.NewBuffer(make([]byte, 0))
+	attrs := []slog.Attr{
+		slog.String("some", "attribute"),
+		slog.Any("err", fmt.Errorf("err")),
+		slog.Group("gr", slog.Int("ik", -23), slog.Duration("dk", 12*time.Second)),
+	}
+	handleNoTime(t, rainbow.New(ansi.NewStripWriter(colored), nil).WithGroup("wg"), slog.LevelWarn, "m", attrs...)
+	handleNoTime(t, rainbow.New(plain, &rainbow.Options{NoColor: true}).WithGroup("wg"), slog.LevelWarn, "m", attrs...)
+	if colored.String() != plain.String() {
+		t.Errorf("stripped output \n%q did not match the plain output \n%q", colored.String(), plain.String())
+	}
+}
+
+// benchRecord has an attr of every common kind
+func benchRecord() slog.Record {
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "request handled", 0)
+	r.AddAttrs(
+		slog.String("method", "GET"),
+		slog.Int("status", 200),
+		slog.Float64("ratio", 0.25),
+		slog.Uint64("bytes", 4096),
+		slog.Bool("cached", true),
+		slog.Duration("took", 12*time.Millisecond),
+		slog.Time("at", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)),
+		slog.Any("err", errors.New("context canceled")),
+	)
+	return r
+}
+
+func TestRainbow_HandleAllocs(t *testing.T) {
+	r := benchRecord()
+	for i, opts := range []*rainbow.Options{
+		nil,
+		{AttrLayout: rainbow.AttrLayoutSingle},
+		{LevelStyle: rainbow.LevelStyleBadge, LevelIcons: rainbow.LevelIconsASCII},
+	} {
+		t.Run(fmt.Sprintf("allocs test %d", i), func(t *testing.T) {
+			h := rainbow.New(io.Discard, opts).WithAttrs([]slog.Attr{slog.String("service", "api")}).WithGroup("req")
+			allocs := testing.AllocsPerRun(100, func() {
+				if err := h.Handle(context.Background(), r); err != nil {
+					t.Fatal(err)
+				}
+			})
+			if allocs != 0 {
+				t.Errorf("handling a record took %v allocations instead of none", allocs)
+			}
+		})
+	}
+}
+
+func BenchmarkRainbow_Handle(b *testing.B) {
+	handlers := []struct {
+		Name    string
+		Handler slog.Handler
+	}{
+		{"rainbow", rainbow.New(io.Discard, nil)},
+		{"rainbow/nocolor", rainbow.New(io.Discard, &rainbow.Options{NoColor: true})},
+		{"rainbow/single", rainbow.New(io.Discard, &rainbow.Options{AttrLayout: rainbow.AttrLayoutSingle})},
+		{"rainbow/context", rainbow.New(io.Discard, nil).WithAttrs([]slog.Attr{slog.String("service", "api")}).WithGroup("req")},
+		{"slog", slog.NewTextHandler(io.Discard, nil)},
+		{"slog/context", slog.NewTextHandler(io.Discard, nil).WithAttrs([]slog.Attr{slog.String("service", "api")}).WithGroup("req")},
+	}
+	r := benchRecord()
+	for _, tt := range handlers {
+		b.Run(tt.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				if err := tt.Handler.Handle(context.Background(), r); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestRainbow_SharedContext(t *testing.T) {
+	tests := []struct {
+		Layout   rainbow.AttrLayout
+		Expected [2]string
+	}{
+		{
+			Layout:   rainbow.AttrLayoutMulti,
+			Expected: [2]string{"m\n\ta=1\n\tg.b=2\n\tg.c=3\n", "m\n\ta=1\n\tg.b=2\n\tg.d=4\n\tg.e=5\n"},
+		},
+		{
+			Layout:   rainbow.AttrLayoutSingle,
+			Expected: [2]string{"m a=1 g.b=2 g.c=3\n", "m a=1 g.b=2 g.d=4 g.e=5\n"},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("shared context test %d", i), func(t *testing.T) {
+			buffer := bytes.NewBuffer(make([]byte, 0))
+			base := rainbow.New(buffer, &rainbow.Options{NoColor: true, AttrLayout: tt.Layout, Layout: "{msg}{attrs}"}).
+				WithAttrs([]slog.Attr{slog.Int("a", 1), {}}).WithGroup("g").WithAttrs([]slog.Attr{slog.Int("b", 2)})
+			handlers := [2]slog.Handler{
+				base.WithAttrs([]slog.Attr{slog.Int("c", 3)}),
+				base.WithAttrs([]slog.Attr{slog.Int("d", 4)}),
+			}
+			// both write the context they share for the first time at once
+			var wg sync.WaitGroup
+			for n, h := range handlers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					r := slog.NewRecord(time.Time{}, slog.LevelInfo, "m", 0)
+					if n == 1 {
+						r.AddAttrs(slog.Int("e", 5))
+					}
+					if err := h.Handle(context.Background(), r); err != nil {
+						t.Error(err)
+					}
+				}()
+			}
+			wg.Wait()
+			output := buffer.String()
+			if output != tt.Expected[0]+tt.Expected[1] && output != tt.Expected[1]+tt.Expected[0] {
+				t.Errorf("output %q did not match the expected output %q", output, tt.Expected[0]+tt.Expected[1])
+			}
+		})
+	}
+}
+
+func BenchmarkRainbow_WithAttrs(b *testing.B) {
+	for _, depth := range []int{1, 16} {
+		b.Run(fmt.Sprintf("depth=%d", depth), func(b *testing.B) {
+			h := rainbow.New(io.Discard, nil)
+			for i := range depth {
+				h = h.WithAttrs([]slog.Attr{slog.Int(fmt.Sprint("k", i), i)})
+			}
+			attrs := []slog.Attr{slog.String("request", "abc")}
+			r := benchRecord()
+			b.ReportAllocs()
+			for range b.N {
+				// a logger made for a request, used once
+				if err := h.WithAttrs(attrs).Handle(context.Background(), r); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
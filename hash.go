@@ -0,0 +1,80 @@
+package rainbow
+
+import (
+	"hash/fnv"
+	"log/slog"
+)
+
+// HashColorOptions pick colors by hashing names or values into a palette,
+// so the same key or request id gets the same color on every line.
+type HashColorOptions struct {
+	// Palette to pick from, defaults to the non-red foreground colors
+	Palette []AnsiMod
+	// Keys colors every key that has no entry in KeyColorOverrides.KeyMap
+	// by hashing its name
+	Keys bool
+	// ValueKeys are key names or globs, matched like NumberFormat.Keys,
+	// whose values are colored by hashing the value, e.g. "request_id"
+	ValueKeys []string
+	// TintLine colors the time and level of a record with the hash color
+	// of the first ValueKeys value found in its attributes
+	TintLine bool
+}
+
+type hashColors struct {
+	palette   []Style
+	keys      bool
+	valueKeys []keyPattern
+	tintLine  bool
+}
+
+func compileHashColors(opts *HashColorOptions) *hashColors {
+	if opts == nil {
+		return nil
+	}
+	hc := &hashColors{
+		keys:      opts.Keys,
+		valueKeys: compileKeyPatterns(opts.ValueKeys),
+		tintLine:  opts.TintLine,
+	}
+	palette := opts.Palette
+	if len(palette) == 0 {
+		palette = []AnsiMod{
+			Mod(Fg.Green),
+			Mod(Fg.Yellow),
+			Mod(Fg.Blue),
+			Mod(Fg.Magenta),
+			Mod(Fg.Cyan),
+			Mod(Fg.HiGreen),
+			Mod(Fg.HiYellow),
+			Mod(Fg.HiBlue),
+			Mod(Fg.HiMagenta),
+			Mod(Fg.HiCyan),
+		}
+	}
+	for _, mod := range palette {
+		hc.palette = append(hc.palette, mod.Style())
+	}
+	return hc
+}
+
+func (hc *hashColors) pick(s string) Style {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(s))
+	return hc.palette[hash.Sum32()%uint32(len(hc.palette))]
+}
+
+func (s *overrideStyler) hashValueColor(groups []string, key string, v slog.Value) (Style, bool) {
+	if s.hashColors == nil || v.Kind() == slog.KindGroup || !matchAnyKey(s.hashColors.valueKeys, groups, key) {
+		return Style{}, false
+	}
+	return s.hashColors.pick(v.String()), true
+}
+
+// tintColor reports the line tint an attribute asks for, if any
+func (h *TextHandler) tintColor(groups []string, a slog.Attr) (Style, bool) {
+	if !h.withColor || h.defaults.hashColors == nil || !h.defaults.hashColors.tintLine {
+		return Style{}, false
+	}
+	return h.defaults.hashValueColor(groups, a.Key, a.Value.Resolve())
+}
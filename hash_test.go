@@ -0,0 +1,77 @@
+package rainbow_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/nerdwave-nick/rainbow"
+)
+
+var hashPalette = []rainbow.AnsiMod{"<p0>", "<p1>", "<p2>", "<p3>", "<p4>"}
+
+func hashRE(s string) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(s))
+	return string(hashPalette[hash.Sum32()%uint32(len(hashPalette))])
+}
+
+func TestRainbow_HashColors(t *testing.T) {
+	opts := opts
+	opts.MessageAttrSeparator = " "
+	opts.AttrAttrSeparator = " "
+	opts.HashColors = &rainbow.HashColorOptions{
+		Palette:   hashPalette,
+		Keys:      true,
+		ValueKeys: []string{"request_id", "*.user"},
+		TintLine:  true,
+	}
+
+	for _, id := range []string{"a1b2", "c3d4", "e5f6", "0000"} {
+		t.Run(fmt.Sprintf("hash color test %s", id), func(t *testing.T) {
+			buffer := bytes.NewBuffer(make([]byte, 0))
+			h := rainbow.New(buffer, &opts).WithAttrs([]slog.Attr{slog.String("request_id", id)})
+			r := slog.NewRecord(time.Time{}, slog.LevelWarn, "m", 0)
+			r.AddAttrs(slog.Group("auth", slog.String("user", "nick")), slog.Int("err", 1))
+			if err := h.Handle(context.Background(), r); err != nil {
+				t.Fatal(err)
+			}
+			expected := hashRE(id) + "|WRN <ro><m>m<ro><so> <ro>" +
+				hashRE("request_id") + "request_id<ro><so>=<ro>" + hashRE(id) + "\"" + id + "\"<ro><so> <ro>" +
+				hashRE("auth") + "auth<ro><so>.<ro>" + hashRE("user") + "user<ro><so>=<ro>" + hashRE("nick") + "\"nick\"<ro><so> <ro>" +
+				"<ke>err<ro><so>=<ro><vi>1<ro>\n"
+			if buffer.String() != expected {
+				t.Errorf("output %q did not match the expected output %q", buffer.String(), expected)
+			}
+		})
+	}
+}
+
+func TestRainbow_HashColorsTintFromRecord(t *testing.T) {
+	opts := opts
+	opts.HashColors = &rainbow.HashColorOptions{
+		Palette:   hashPalette,
+		ValueKeys: []string{"trace_id"},
+		TintLine:  true,
+	}
+
+	buffer := bytes.NewBuffer(make([]byte, 0))
+	h := rainbow.New(buffer, &opts)
+	r := slog.NewRecord(time.Unix(0, 0), slog.LevelDebug, "m", 0)
+	r.AddAttrs(slog.String("trace_id", "t-1"))
+	if err := h.Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	tint := hashRE("t-1")
+	expected := tint + "1970-01-01T"
+	if !bytes.HasPrefix(buffer.Bytes(), []byte(expected)) {
+		t.Errorf("output %q did not start with %q", buffer.String(), expected)
+	}
+	if !bytes.Contains(buffer.Bytes(), []byte(tint+"|DBG <ro>")) {
+		t.Errorf("output %q did not contain a tinted level", buffer.String())
+	}
+}
@@ -0,0 +1,478 @@
+package rainbow
+
+import (
+	"strings"
+
+	"github.com/nerdwave-nick/rainbow/ansi"
+)
+
+// HighlightColorOverrides are the styles of the tokens
+// highlighted inside of messages and string values
+type HighlightColorOverrides struct {
+	Number   AnsiMod `json:"number,omitempty"`
+	Quoted   AnsiMod `json:"quoted,omitempty"`
+	URL      AnsiMod `json:"url,omitempty"`
+	IP       AnsiMod `json:"ip,omitempty"`
+	UUID     AnsiMod `json:"uuid,omitempty"`
+	Path     AnsiMod `json:"path,omitempty"`
+	Duration AnsiMod `json:"duration,omitempty"`
+	// GET, POST and the other HTTP methods
+	Method AnsiMod `json:"method,omitempty"`
+}
+
+func getOrDefaultHighlightOverrides(highlightOverrides *HighlightColorOverrides) *HighlightColorOverrides {
+	if highlightOverrides != nil {
+		return highlightOverrides
+	}
+	return &HighlightColorOverrides{
+		Number:   Mod(Fg.Yellow),
+		Quoted:   Mod(Fg.Green),
+		URL:      Mod(Fg.Blue, Fmt.Underline),
+		IP:       Mod(Fg.Magenta),
+		UUID:     Mod(Fg.HiMagenta),
+		Path:     Mod(Fg.HiCyan),
+		Duration: Mod(Fg.Cyan),
+		Method:   Mod(Fmt.Bold),
+	}
+}
+
+type tokenKind int
+
+const (
+	tokenNone tokenKind = iota
+	tokenNumber
+	tokenQuoted
+	tokenURL
+	tokenIP
+	tokenUUID
+	tokenPath
+	tokenDuration
+	tokenMethod
+	tokenKinds
+)
+
+type highlighter struct {
+	styles [tokenKinds]Style
+}
+
+func compileHighlighter(enabled bool, overrides *HighlightColorOverrides) *highlighter {
+	if !enabled {
+		return nil
+	}
+	o := getOrDefaultHighlightOverrides(overrides)
+	return &highlighter{styles: [tokenKinds]Style{
+		tokenNumber:   o.Number.Style(),
+		tokenQuoted:   o.Quoted.Style(),
+		tokenURL:      o.URL.Style(),
+		tokenIP:       o.IP.Style(),
+		tokenUUID:     o.UUID.Style(),
+		tokenPath:     o.Path.Style(),
+		tokenDuration: o.Duration.Style(),
+		tokenMethod:   o.Method.Style(),
+	}}
+}
+
+// appendHighlighted writes text, which is shown in base,
+// with the tokens in it in their own styles. URLs and paths
+// are made links too, unless text is part of a link already.
+func (h *TextHandler) appendHighlighted(buf []byte, text string, base Style, link bool) []byte {
+	highlight := h.highlight != nil && h.withColor
+	link = link && h.links != nil
+	if !highlight && !link {
+		return append(buf, text...)
+	}
+	last := 0
+	scanTokens(text, func(kind tokenKind, start, end int) {
+		var st Style
+		if highlight {
+			st = h.highlight.styles[kind]
+		}
+		target := ""
+		if link {
+			target = h.links.tokenTarget(kind, text[start:end])
+		}
+		if st.IsZero() && target == "" {
+			return
+		}
+		buf = append(buf, text[last:start]...)
+		if target != "" {
+			buf = appendLinkStart(buf, target)
+		}
+		if st.IsZero() {
+			buf = append(buf, text[start:end]...)
+		} else {
+			buf = st.AppendSGR(buf)
+			buf = append(buf, text[start:end]...)
+			buf = append(buf, h.resetMod...)
+			buf = base.AppendSGR(buf)
+		}
+		if target != "" {
+			buf = appendLinkEnd(buf)
+		}
+		last = end
+	})
+	return append(buf, text[last:]...)
+}
+
+// scanTokens finds the tokens of text in a single pass. Tokens start
+// and end at word boundaries, so nothing is found in the middle of a
+// word. Escape sequences are skipped, and so are the texts of links.
+func scanTokens(text string, emit func(kind tokenKind, start, end int)) {
+	inLink := false
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\x1b':
+			n := ansi.SequenceLen(text[i:])
+			if strings.HasPrefix(text[i:], "\x1b]8;") {
+				// a link starts with a target and ends without one
+				params, _ := strings.CutSuffix(strings.TrimSuffix(text[i+4:i+n], "\a"), "\x1b\\")
+				_, target, _ := strings.Cut(params, ";")
+				inLink = target != ""
+			}
+			i += n
+			continue
+		case inLink:
+			i++
+			continue
+		case c == '"' || c == '\'' || c == '`':
+			if end := quotedEnd(text, i); end != -1 {
+				emit(tokenQuoted, i, end)
+				i = end
+				continue
+			}
+			i++
+			continue
+		case isSpace(c) || isPunct(c):
+			i++
+			continue
+		}
+
+		if end := urlEnd(text, i); end != -1 {
+			emit(tokenURL, i, end)
+			i = end
+			continue
+		}
+		end := wordEnd(text, i)
+		if kind := classifyWord(text[i:end]); kind != tokenNone {
+			emit(kind, i, end)
+		}
+		i = end
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// isPunct are the characters that end a word
+func isPunct(c byte) bool {
+	switch c {
+	case '(', ')', '[', ']', '{', '}', '<', '>', ',', ';', '=', '"', '\'', '`', '\x1b':
+		return true
+	}
+	return false
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c >= 0x80
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHex(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// wordEnd finds the end of the word at i, leaving off
+// the punctuation that ends a sentence
+func wordEnd(text string, i int) int {
+	end := i
+	for end < len(text) && !isSpace(text[end]) && !isPunct(text[end]) {
+		end++
+	}
+	for end > i+1 && strings.IndexByte(".:!?", text[end-1]) != -1 {
+		end--
+	}
+	return end
+}
+
+// quotedEnd finds the closing quote of a quote starting a word,
+// -1 if there is none on the same line
+func quotedEnd(text string, i int) int {
+	if i > 0 && isWordByte(text[i-1]) {
+		// an apostrophe, like in don't
+		return -1
+	}
+	q := text[i]
+	for j := i + 1; j < len(text); j++ {
+		switch text[j] {
+		case '\\':
+			j++
+		case '\n', '\x1b':
+			return -1
+		case q:
+			if j+1 < len(text) && isWordByte(text[j+1]) {
+				return -1
+			}
+			return j + 1
+		}
+	}
+	return -1
+}
+
+// urlEnd finds the end of a URL starting at i, like https://
+// or postgres://, -1 if there is none
+func urlEnd(text string, i int) int {
+	j := i
+	for j < len(text) && (isWordByte(text[j]) && text[j] < 0x80 || text[j] == '+' || text[j] == '-' || text[j] == '.') {
+		j++
+	}
+	if j == i || !strings.HasPrefix(text[j:], "://") || !(text[i] >= 'a' && text[i] <= 'z' || text[i] >= 'A' && text[i] <= 'Z') {
+		return -1
+	}
+	end := j + 3
+	// control bytes end it too, in a link target they would end the link
+	for end < len(text) && text[end] > ' ' && text[end] != 0x7f && strings.IndexByte("\"'`<>", text[end]) == -1 {
+		end++
+	}
+	for end > j+3 && strings.IndexByte(".,:;!?)]}", text[end-1]) != -1 {
+		end--
+	}
+	if end == j+3 {
+		return -1
+	}
+	return end
+}
+
+var httpMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true,
+	"DELETE": true, "OPTIONS": true, "CONNECT": true, "TRACE": true,
+}
+
+// classifyWord tells what kind of token a word is, the cheap
+// checks on the first byte go first so most words are quickly done
+func classifyWord(w string) tokenKind {
+	c := w[0]
+	switch {
+	case c == '/' && len(w) > 1,
+		strings.HasPrefix(w, "./"), strings.HasPrefix(w, "../"), strings.HasPrefix(w, "~/"):
+		return tokenPath
+	case c >= 'A' && c <= 'Z' && httpMethods[w]:
+		return tokenMethod
+	}
+	if len(w) == 36 && isUUID(w) {
+		return tokenUUID
+	}
+	if isHex(c) || c == ':' {
+		if strings.IndexByte(w, ':') != -1 || strings.Count(w, ".") == 3 {
+			if isIPv4(w) || isIPv4Port(w) || isIPv6(w) {
+				return tokenIP
+			}
+		}
+	}
+	if !isDigit(c) && !((c == '-' || c == '+' || c == '.') && len(w) > 1 && isDigit(w[1])) {
+		return tokenNone
+	}
+	if isNumber(w) {
+		return tokenNumber
+	}
+	if isDuration(w) {
+		return tokenDuration
+	}
+	return tokenNone
+}
+
+func isUUID(w string) bool {
+	for i := 0; i < len(w); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if w[i] != '-' {
+				return false
+			}
+		default:
+			if !isHex(w[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// the scanners below only look at the bytes, parsing the words
+// would allocate an error for every one that doesn't match
+
+// isNumber takes decimal ints, floats and percentages
+func isNumber(w string) bool {
+	w = strings.TrimSuffix(w, "%")
+	w = trimSign(w)
+	n := decimalLen(w)
+	if n == 0 {
+		return false
+	}
+	w = w[n:]
+	if w != "" && (w[0] == 'e' || w[0] == 'E') {
+		w = trimSign(w[1:])
+		n = digitsLen(w)
+		if n == 0 {
+			return false
+		}
+		w = w[n:]
+	}
+	return w == ""
+}
+
+// isDuration takes what time.ParseDuration does, like 1.5s or 1h30m
+func isDuration(w string) bool {
+	w = trimSign(w)
+	if w == "" {
+		return false
+	}
+	for w != "" {
+		n := decimalLen(w)
+		if n == 0 {
+			return false
+		}
+		w = w[n:]
+		n = durationUnitLen(w)
+		if n == 0 {
+			return false
+		}
+		w = w[n:]
+	}
+	return true
+}
+
+func durationUnitLen(w string) int {
+	for _, unit := range [...]string{"ns", "us", "µs", "μs", "ms", "s", "m", "h"} {
+		if strings.HasPrefix(w, unit) {
+			return len(unit)
+		}
+	}
+	return 0
+}
+
+func trimSign(w string) string {
+	if w != "" && (w[0] == '-' || w[0] == '+') {
+		return w[1:]
+	}
+	return w
+}
+
+func digitsLen(w string) int {
+	n := 0
+	for n < len(w) && isDigit(w[n]) {
+		n++
+	}
+	return n
+}
+
+// digitsValue is the value of a few digits
+func digitsValue(w string) int {
+	v := 0
+	for i := 0; i < len(w); i++ {
+		v = v*10 + int(w[i]-'0')
+	}
+	return v
+}
+
+// decimalLen is the length of the digits with an optional
+// fraction w starts with, 0 if there isn't a digit
+func decimalLen(w string) int {
+	n := digitsLen(w)
+	if n < len(w) && w[n] == '.' {
+		if f := digitsLen(w[n+1:]); n > 0 || f > 0 {
+			return n + 1 + f
+		}
+	}
+	return n
+}
+
+// isIPv4 takes dotted quads like 10.0.0.1, without leading zeros
+func isIPv4(w string) bool {
+	for part := range 4 {
+		if part > 0 {
+			if w == "" || w[0] != '.' {
+				return false
+			}
+			w = w[1:]
+		}
+		n := digitsLen(w)
+		if n == 0 || n > 3 || n > 1 && w[0] == '0' || digitsValue(w[:n]) > 255 {
+			return false
+		}
+		w = w[n:]
+	}
+	return w == ""
+}
+
+// isIPv4Port takes an IPv4 with a port, like 10.0.0.1:8080
+func isIPv4Port(w string) bool {
+	i := strings.LastIndexByte(w, ':')
+	if i == -1 || !isIPv4(w[:i]) {
+		return false
+	}
+	port := w[i+1:]
+	if n := digitsLen(port); n == 0 || n != len(port) || n > 5 {
+		return false
+	}
+	return digitsValue(port) <= 65535
+}
+
+// isIPv6 takes colon hex addresses, compressed with :: or not,
+// ending in an IPv4 or not, with a zone or not
+func isIPv6(w string) bool {
+	if i := strings.IndexByte(w, '%'); i != -1 {
+		if i == len(w)-1 {
+			return false
+		}
+		w = w[:i]
+	}
+	groups := 0
+	compressed := false
+	if strings.HasPrefix(w, "::") {
+		compressed = true
+		w = w[2:]
+	} else if strings.HasPrefix(w, ":") {
+		return false
+	}
+	for w != "" {
+		n := 0
+		for n < len(w) && isHex(w[n]) {
+			n++
+		}
+		if n < len(w) && w[n] == '.' {
+			// the last 32 bits written as IPv4
+			if !isIPv4(w) {
+				return false
+			}
+			groups += 2
+			break
+		}
+		if n == 0 || n > 4 {
+			return false
+		}
+		groups++
+		w = w[n:]
+		if w == "" {
+			break
+		}
+		if w[0] != ':' || len(w) == 1 {
+			return false
+		}
+		w = w[1:]
+		if w[0] == ':' {
+			if compressed {
+				return false
+			}
+			compressed = true
+			w = w[1:]
+		}
+	}
+	if compressed {
+		return groups < 8
+	}
+	return groups == 8
+}
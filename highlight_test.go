@@ -0,0 +1,130 @@
+package rainbow_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/nerdwave-nick/rainbow"
+)
+
+var highlightOverrides = &rainbow.HighlightColorOverrides{
+	Number:   "<hn>",
+	Quoted:   "<hq>",
+	URL:      "<hu>",
+	IP:       "<hi>",
+	UUID:     "<hid>",
+	Path:     "<hp>",
+	Duration: "<hd>",
+	Method:   "<hm>",
+}
+
+func TestRainbow_Highlight(t *testing.T) {
+	tests := []struct {
+		Message string
+		Output  string
+	}{
+		{
+			Message: "took 1.5s for 3 items, 50% done",
+			Output:  "<m>took <hd>1.5s<ro><m> for <hn>3<ro><m> items, <hn>50%<ro><m> done<ro>\n",
+		},
+		{
+			Message: "GET https://example.com/a?b=1. from 10.0.0.1:8080 and [::1]",
+			Output:  "<m><hm>GET<ro><m> <hu>https://example.com/a?b=1<ro><m>. from <hi>10.0.0.1:8080<ro><m> and [<hi>::1<ro><m>]<ro>\n",
+		},
+		{
+			Message: `open "my file" at /etc/hosts or ./rel/path, don't`,
+			Output:  "<m>open <hq>\"my file\"<ro><m> at <hp>/etc/hosts<ro><m> or <hp>./rel/path<ro><m>, don't<ro>\n",
+		},
+		{
+			Message: "id 123e4567-e89b-12d3-a456-426614174000 ok",
+			Output:  "<m>id <hid>123e4567-e89b-12d3-a456-426614174000<ro><m> ok<ro>\n",
+		},
+		{
+			// nothing in the middle of words
+			Message: "v2 abc123 2024-01-02 12:30 GETTER a/b",
+			Output:  "<m>v2 abc123 2024-01-02 12:30 GETTER a/b<ro>\n",
+		},
+		{
+			// decimal numbers only
+			Message: "0x1F 0b1 1_000 1e3 -2.5 .5 1.2.3 Inf",
+			Output:  "<m>0x1F 0b1 1_000 <hn>1e3<ro><m> <hn>-2.5<ro><m> <hn>.5<ro><m> 1.2.3 Inf<ro>\n",
+		},
+		{
+			Message: "1h30m 2µs 5x 1.5.s -3ms",
+			Output:  "<m><hd>1h30m<ro><m> <hd>2µs<ro><m> 5x 1.5.s <hd>-3ms<ro><m><ro>\n",
+		},
+		{
+			Message: "fe80::1%eth0 2001:db8::8a2e:370:7334 ::ffff:10.0.0.1 1:2:3:4:5:6:7:8 1:2 256.0.0.1 01.0.0.1 1.2.3.4:70000 a::b::c",
+			Output:  "<m><hi>fe80::1%eth0<ro><m> <hi>2001:db8::8a2e:370:7334<ro><m> <hi>::ffff:10.0.0.1<ro><m> <hi>1:2:3:4:5:6:7:8<ro><m> 1:2 256.0.0.1 01.0.0.1 1.2.3.4:70000 a::b::c<ro>\n",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("highlight test %d", i), func(t *testing.T) {
+			buffer := bytes.NewBuffer(make([]byte, 0))
+			o := opts
+			o.Layout = "{msg}"
+			o.Highlight = true
+			o.HighlightOverrides = highlightOverrides
+			h := rainbow.New(buffer, &o)
+			handleNoTime(t, h, slog.LevelInfo, tt.Message)
+			if buffer.String() != tt.Output {
+				t.Errorf("output %q did not match the expected output %q", buffer.String(), tt.Output)
+			}
+		})
+	}
+}
+
+func TestRainbow_HighlightValues(t *testing.T) {
+	buffer := bytes.NewBuffer(make([]byte, 0))
+	o := opts
+	o.Layout = "{attrs}"
+	o.AttrLayout = rainbow.AttrLayoutSingle
+	o.Highlight = true
+	o.HighlightOverrides = highlightOverrides
+	h := rainbow.New(buffer, &o)
+	handleNoTime(t, h, slog.LevelInfo, "m", slog.String("q", "took 5ms"), slog.Int("n", 5))
+	// inside of the quotes, other values stay as they are
+	expected := "<kd>q<ro><so>=<ro><vs>\"took <hd>5ms<ro><vs>\"<ro><so> <ro><kd>n<ro><so>=<ro><vi>5<ro>\n"
+	if buffer.String() != expected {
+		t.Errorf("output %q did not match the expected output %q", buffer.String(), expected)
+	}
+}
+
+func TestRainbow_HighlightAllocs(t *testing.T) {
+	// words that aren't tokens cost nothing either
+	msg := "GET /api/users/42 from 10.0.0.1 took 12.5ms, status 200 0x1F v2 1.2.3 abc:def for request 123e4567-e89b-12d3-a456-426614174000"
+	h := rainbow.New(io.Discard, &rainbow.Options{Highlight: true, AttrLayout: rainbow.AttrLayoutSingle})
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, msg, 0)
+	r.AddAttrs(slog.String("user", "alice 12:30"), slog.Int("n", 3))
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := h.Handle(context.Background(), r); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("highlighting a record took %v allocations instead of none", allocs)
+	}
+}
+
+func BenchmarkRainbow_Highlight(b *testing.B) {
+	msg := "GET /api/users/42 from 10.0.0.1 took 12.5ms, status 200 for request 123e4567-e89b-12d3-a456-426614174000"
+	for _, highlight := range []bool{false, true} {
+		b.Run(fmt.Sprintf("highlight=%t", highlight), func(b *testing.B) {
+			h := rainbow.New(io.Discard, &rainbow.Options{Highlight: highlight, AttrLayout: rainbow.AttrLayoutSingle})
+			r := slog.NewRecord(time.Now(), slog.LevelInfo, msg, 0)
+			r.AddAttrs(slog.String("user", "alice"), slog.Int("n", 3))
+			b.ReportAllocs()
+			for range b.N {
+				if err := h.Handle(context.Background(), r); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
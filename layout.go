@@ -0,0 +1,367 @@
+package rainbow
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/nerdwave-nick/rainbow/ansi"
+)
+
+// DefaultLayout is the layout used when Options.Layout is empty
+const DefaultLayout = "{time}{level:bar}{msg}{attrs}"
+
+const defaultTimeFormat = "2006-01-02T15:04:05.000"
+
+type layoutFieldKind int
+
+const (
+	layoutLiteral layoutFieldKind = iota
+	layoutTime
+	layoutLevel
+	layoutMessage
+	layoutSource
+	layoutAttrs
+)
+
+var layoutFieldNames = map[string]layoutFieldKind{
+	"time":    layoutTime,
+	"level":   layoutLevel,
+	"msg":     layoutMessage,
+	"message": layoutMessage,
+	"source":  layoutSource,
+	"attrs":   layoutAttrs,
+}
+
+// formats the fields understand, time takes any time layout
+var layoutFormats = map[layoutFieldKind][]string{
+	layoutLevel:   {"", "short", "long", "char", "bar"},
+	layoutMessage: {""},
+	layoutSource:  {"", "short", "long"},
+	layoutAttrs:   {""},
+}
+
+type layoutField struct {
+	kind layoutFieldKind
+	// the text of literals, the format of fields
+	text string
+	// pads the field to this many columns, 0 for no padding
+	width int
+	// '-' for left, '^' for centered, 0 for right aligned
+	align byte
+}
+
+// ValidateLayout reports the first problem with a layout template,
+// see Options.Layout for the syntax. New writes fields it doesn't
+// understand as they are, so they show up in the output.
+func ValidateLayout(layout string) error {
+	_, err := compileLayout(layout)
+	return err
+}
+
+func compileLayout(layout string) ([]layoutField, error) {
+	if layout == "" {
+		layout = DefaultLayout
+	}
+	var fields []layoutField
+	var firstErr error
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			fields = append(fields, layoutField{kind: layoutLiteral, text: literal.String()})
+			literal.Reset()
+		}
+	}
+	for i := 0; i < len(layout); i++ {
+		c := layout[i]
+		if (c == '{' || c == '}') && i+1 < len(layout) && layout[i+1] == c {
+			literal.WriteByte(c)
+			i++
+			continue
+		}
+		if c != '{' {
+			literal.WriteByte(c)
+			continue
+		}
+		end := strings.IndexByte(layout[i:], '}')
+		if end == -1 {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("rainbow: unclosed { at %d in layout %q", i, layout)
+			}
+			literal.WriteString(layout[i:])
+			break
+		}
+		f, err := parseLayoutField(layout[i+1 : i+end])
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("rainbow: layout %q: %w", layout, err)
+			}
+			literal.WriteString(layout[i : i+end+1])
+		} else {
+			flush()
+			fields = append(fields, f)
+		}
+		i += end
+	}
+	flush()
+	return fields, firstErr
+}
+
+// parseLayoutField parses name[,width][:format]
+func parseLayoutField(s string) (layoutField, error) {
+	spec, format, _ := strings.Cut(s, ":")
+	name, width, hasWidth := strings.Cut(spec, ",")
+	kind, ok := layoutFieldNames[strings.TrimSpace(name)]
+	if !ok {
+		return layoutField{}, fmt.Errorf("unknown field %q", name)
+	}
+	f := layoutField{kind: kind, text: format}
+	if formats, ok := layoutFormats[kind]; ok && !slices.Contains(formats, format) {
+		return layoutField{}, fmt.Errorf("unknown format %q for %s", format, name)
+	}
+	if kind == layoutTime && format == "" {
+		f.text = defaultTimeFormat
+	}
+	if hasWidth {
+		width = strings.TrimSpace(width)
+		if strings.HasPrefix(width, "-") || strings.HasPrefix(width, "^") {
+			f.align = width[0]
+			width = width[1:]
+		}
+		n, err := strconv.Atoi(width)
+		if err != nil || n <= 0 {
+			return layoutField{}, fmt.Errorf("bad width %q for %s", width, name)
+		}
+		f.width = n
+	}
+	return f, nil
+}
+
+// appendLayout writes a record the way the layout says. Text right
+// after a field that comes out empty is left out along with it, so
+// optional fields don't leave their separators behind. Text glued to
+// the front of it, like the brackets of " [{source}]", goes too, with
+// the blanks before it, and only the glued part of the text after.
+// Blanks left at the end of the line by left out fields are trimmed.
+func (h *TextHandler) appendLayout(buf []byte, r slog.Record, hs *handleState) []byte {
+	hasAttrs := len(hs.Context) > 0 || r.NumAttrs() > 0
+	inPlace := hs.Inline || h.attrsInPlace
+	skipLiteral := false
+	// the left out field was opened with text, so only the
+	// closing part of the text after it is left out
+	closeOnly := false
+	// the text right before the current field and where it
+	// was written, for taking back its opening part
+	literal, literalStart := "", 0
+	// the last text written with no field after it, trimmed at the
+	// end of the line if it's followed by fields that were left out
+	tail, tailStart := "", 0
+	trimTail := false
+	for i, f := range h.layout {
+		if f.kind == layoutLiteral {
+			literal = ""
+			// the text leading up to attrs on lines of their own
+			if !inPlace && h.onlyAttrsAfter(i) {
+				continue
+			}
+			text := f.text
+			switch {
+			case skipLiteral && closeOnly:
+				text = strings.TrimLeftFunc(text, isNotBlank)
+			case skipLiteral:
+				text = ""
+			}
+			if text != "" {
+				literal, literalStart = text, len(buf)
+				tail, tailStart, trimTail = text, len(buf), false
+				buf = h.appendSymbol(buf, text)
+			}
+			skipLiteral = false
+			continue
+		}
+
+		present := true
+		switch f.kind {
+		case layoutTime:
+			present = !r.Time.IsZero()
+		case layoutSource:
+			present = r.PC != 0
+		case layoutAttrs:
+			// attrs on lines of their own follow the whole
+			// line, unless the layout is one of its own
+			present = hasAttrs && inPlace
+		}
+		// padded fields keep their space, to keep columns in line
+		skipLiteral = !present && f.width == 0
+		if skipLiteral {
+			opening := literal[len(strings.TrimRightFunc(literal, isNotBlank)):]
+			closeOnly = opening != ""
+			if closeOnly {
+				buf = buf[:literalStart]
+				tail = strings.TrimRightFunc(literal[:len(literal)-len(opening)], unicode.IsSpace)
+				if tail != "" {
+					buf = h.appendSymbol(buf, tail)
+				}
+			}
+			trimTail = tail != ""
+			literal = ""
+			continue
+		}
+		literal, tail = "", ""
+
+		start := len(buf)
+		if present {
+			switch f.kind {
+			case layoutTime:
+				buf = h.appendRecordTime(buf, r.Time.Round(0), f.text, hs)
+			case layoutLevel:
+				buf = h.appendRecordLevel(buf, r.Level, f.text, hs)
+			case layoutMessage:
+				hs.MessageStart = len(buf)
+				msgCol := h.style(StyleRequest{Element: ElementMessage, Level: r.Level}, hs)
+				buf = msgCol.AppendSGR(buf)
+				buf = h.appendHighlighted(buf, r.Message, msgCol, true)
+				buf = append(buf, h.resetMod...)
+			case layoutSource:
+				buf = h.appendSource(buf, r.PC, f.text, hs)
+			case layoutAttrs:
+				// without text of its own in between, the
+				// message separator sets the attrs apart
+				if i > 0 && h.layout[i-1].kind != layoutLiteral {
+					buf = append(buf, h.messageSeparator(hs)...)
+					start = len(buf)
+				}
+				buf = h.appendRecordAttrs(buf, r, hs)
+			}
+		}
+		switch {
+		case f.width > 0:
+			buf = padField(buf, start, f.width, f.align)
+		case f.kind == layoutMessage && hs.MessageWidth > 0:
+			buf = padField(buf, start, hs.MessageWidth, '-')
+		case f.kind == layoutLevel && hs.LevelWidth > 0:
+			buf = padField(buf, start, hs.LevelWidth, '-')
+		}
+	}
+	if trimTail {
+		if kept := strings.TrimRightFunc(tail, unicode.IsSpace); kept != tail {
+			buf = buf[:tailStart]
+			if kept != "" {
+				buf = h.appendSymbol(buf, kept)
+			}
+		}
+	}
+	if hasAttrs && !inPlace && h.hasAttrsField() {
+		buf = append(buf, h.messageSeparator(hs)...)
+		buf = h.appendRecordAttrs(buf, r, hs)
+	}
+	return buf
+}
+
+// onlyAttrsAfter tells if the attrs are the next field and the last one
+func (h *TextHandler) onlyAttrsAfter(i int) bool {
+	seenAttrs := false
+	for _, f := range h.layout[i+1:] {
+		switch {
+		case f.kind == layoutAttrs && !seenAttrs:
+			seenAttrs = true
+		case f.kind != layoutLiteral:
+			return false
+		}
+	}
+	return seenAttrs
+}
+
+func (h *TextHandler) hasAttrsField() bool {
+	for _, f := range h.layout {
+		if f.kind == layoutAttrs {
+			return true
+		}
+	}
+	return false
+}
+
+func isNotBlank(r rune) bool {
+	return !unicode.IsSpace(r)
+}
+
+func (h *TextHandler) appendSymbol(buf []byte, text string) []byte {
+	buf = append(buf, h.symbolMod...)
+	buf = append(buf, text...)
+	return append(buf, h.resetMod...)
+}
+
+// padField pads what was written since start to width columns,
+// the spaces are put outside of any styles
+func padField(buf []byte, start, width int, align byte) []byte {
+	n := width - ansi.Width(string(buf[start:]))
+	if n <= 0 {
+		return buf
+	}
+	switch align {
+	case '-':
+		return append(buf, strings.Repeat(" ", n)...)
+	case '^':
+		buf = slices.Insert(buf, start, bytes.Repeat([]byte{' '}, n/2)...)
+		return append(buf, strings.Repeat(" ", n-n/2)...)
+	default:
+		return slices.Insert(buf, start, bytes.Repeat([]byte{' '}, n)...)
+	}
+}
+
+// appendSource writes the file and line of the log call,
+// short is the file and its directory
+func (h *TextHandler) appendSource(buf []byte, pc uintptr, format string, hs *handleState) []byte {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	file := frame.File
+	if format != "long" {
+		file = filepath.Join(filepath.Base(filepath.Dir(file)), filepath.Base(file))
+	}
+	source := file + ":" + strconv.Itoa(frame.Line)
+	col := h.style(StyleRequest{Element: ElementSource, Value: slog.StringValue(source), Level: hs.Level}, hs)
+	if h.links != nil && filepath.IsAbs(frame.File) {
+		buf = appendLinkStart(buf, h.links.fileTarget(frame.File))
+		buf = h.appendStyled(buf, col, source)
+		return appendLinkEnd(buf)
+	}
+	return h.appendStyled(buf, col, source)
+}
+
+// appendRecordAttrs writes the attrs from WithAttrs and the record
+func (h *TextHandler) appendRecordAttrs(buf []byte, r slog.Record, hs *handleState) []byte {
+	if h.alignKeys && !hs.Inline {
+		hs.KeyWidth = h.keyColumn(r, hs)
+	}
+	if hs.Inline && len(hs.Pinned) > 0 {
+		return h.appendPinnedAttrs(buf, r, hs)
+	}
+	if h.groupStyle != GroupStyleDotted && !hs.Inline {
+		first := true
+		return h.appendTree(buf, h.attrTree(r, hs), "", &first, hs)
+	}
+	if len(hs.Context) > 0 {
+		buf = h.appendContext(buf, hs)
+		if r.NumAttrs() > 0 {
+			buf = append(buf, h.attrSeparator(hs)...)
+		}
+	}
+
+	numAttrs := r.NumAttrs()
+	curAttrs := 0
+	r.Attrs(func(a slog.Attr) bool {
+		curAttrs++
+		a.Value = a.Value.Resolve()
+		buf = h.appendAttr(buf, a, hs)
+		if curAttrs < numAttrs {
+			buf = append(buf, h.attrSeparator(hs)...)
+		}
+		return true
+	})
+	return buf
+}
@@ -0,0 +1,156 @@
+package rainbow_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/nerdwave-nick/rainbow"
+)
+
+func TestRainbow_Layout(t *testing.T) {
+	recordTime := time.Date(2024, 3, 14, 15, 9, 26, 0, time.UTC)
+	tests := []struct {
+		Layout     string
+		NoColor    bool
+		AttrLayout rainbow.AttrLayout
+		Time       time.Time
+		Level      slog.Level
+		Attrs      []slog.Attr
+		Output     string
+	}{
+		{
+			Layout: "{level} {time:15:04:05} {msg} {attrs}",
+			Time:   recordTime,
+			Level:  slog.LevelWarn,
+			Attrs:  []slog.Attr{slog.Int("n", 1)},
+			Output: "<lw>WRN<ro><so> <ro><t>15:09:26<ro><so> <ro><m>m<ro><so> <ro><kd>n<ro><so>=<ro><vi>1<ro>\n",
+		},
+		{
+			// no time, so the space after it goes too
+			Layout: "{level} {time:15:04:05} {msg}",
+			Level:  slog.LevelWarn,
+			Output: "<lw>WRN<ro><so> <ro><m>m<ro>\n",
+		},
+		{
+			// the message separator only without text in between
+			Layout: "[{level:char}] {msg}{attrs}",
+			Level:  slog.LevelError,
+			Attrs:  []slog.Attr{slog.Int("n", 1)},
+			Output: "<so>[<ro><le>E<ro><so>] <ro><m>m<ro><so><mas><ro><kd>n<ro><so>=<ro><vi>1<ro>\n",
+		},
+		{
+			Layout:     "{level,-5:long}|{msg,6}|{attrs,^9}|",
+			NoColor:    true,
+			AttrLayout: rainbow.AttrLayoutSingle,
+			Level:      slog.LevelInfo,
+			Attrs:      []slog.Attr{slog.Int("n", 1)},
+			Output:     "INFO |     m|   n=1   |\n",
+		},
+		{
+			// padded fields keep their space when empty
+			Layout:     "{time,8:15:04:05}|{level:long}|{attrs,-4}|",
+			NoColor:    true,
+			AttrLayout: rainbow.AttrLayoutSingle,
+			Level:      slog.LevelDebug + 1,
+			Output:     "        |DEBUG+1|    |\n",
+		},
+		{
+			// no source, so its brackets go along with it
+			Layout:  "{msg} [{source}]",
+			NoColor: true,
+			Level:   slog.LevelInfo,
+			Output:  "m\n",
+		},
+		{
+			// the space after the brackets stays
+			Layout:     "{level} ({source}) {msg} [{source}] {attrs}",
+			NoColor:    true,
+			AttrLayout: rainbow.AttrLayoutSingle,
+			Level:      slog.LevelInfo,
+			Attrs:      []slog.Attr{slog.Int("n", 1)},
+			Output:     "INF m n=1\n",
+		},
+		{
+			Layout: "{level} - [{source}] {msg}",
+			Level:  slog.LevelWarn,
+			Output: "<lw>WRN<ro><so> -<ro><so> <ro><m>m<ro>\n",
+		},
+		{
+			// nothing left behind at the end of the line
+			Layout:     "{level} {msg} {attrs}",
+			NoColor:    true,
+			AttrLayout: rainbow.AttrLayoutSingle,
+			Level:      slog.LevelInfo,
+			Output:     "INF m\n",
+		},
+		{
+			Layout:  "{level} {msg} {source}",
+			NoColor: true,
+			Level:   slog.LevelInfo,
+			Output:  "INF m\n",
+		},
+		{
+			Layout:     "{msg} [{source}] {attrs}",
+			NoColor:    true,
+			AttrLayout: rainbow.AttrLayoutSingle,
+			Level:      slog.LevelInfo,
+			Output:     "m\n",
+		},
+		{
+			Layout: "{level} {msg} | {source} ",
+			Level:  slog.LevelWarn,
+			Output: "<lw>WRN<ro><so> <ro><m>m<ro><so> |<ro>\n",
+		},
+		{
+			Layout:  "{{{level}}} {bogus} {msg",
+			NoColor: true,
+			Level:   slog.LevelInfo,
+			Output:  "{INF} {bogus} {msg\n",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("layout test %d", i), func(t *testing.T) {
+			buffer := bytes.NewBuffer(make([]byte, 0))
+			opts := opts
+			opts.Layout = tt.Layout
+			opts.NoColor = tt.NoColor
+			opts.AttrLayout = tt.AttrLayout
+			r := slog.NewRecord(tt.Time, tt.Level, "m", 0)
+			r.AddAttrs(tt.Attrs...)
+			if err := rainbow.New(buffer, &opts).Handle(context.Background(), r); err != nil {
+				t.Fatal(err)
+			}
+			if buffer.String() != tt.Output {
+				t.Errorf("output %q did not match the expected output %q", buffer.String(), tt.Output)
+			}
+		})
+	}
+}
+
+func TestRainbow_LayoutSource(t *testing.T) {
+	buffer := bytes.NewBuffer(make([]byte, 0))
+	logger := slog.New(rainbow.New(buffer, &rainbow.Options{NoColor: true, Layout: "{source} {msg}"}))
+	logger.Info("m")
+	if !regexp.MustCompile(`^[^/]+/layout_test\.go:\d+ m\n$`).MatchString(buffer.String()) {
+		t.Errorf("output %q did not have the source", buffer.String())
+	}
+}
+
+func TestRainbow_ValidateLayout(t *testing.T) {
+	for _, layout := range []string{"", rainbow.DefaultLayout, "{time,-20:15:04:05,000} {level,^5:long} {msg}"} {
+		if err := rainbow.ValidateLayout(layout); err != nil {
+			t.Errorf("layout %q: %v", layout, err)
+		}
+	}
+	for _, layout := range []string{"{bogus}", "{msg", "{msg,0}", "{level:huge}", "{msg,x}"} {
+		if err := rainbow.ValidateLayout(layout); err == nil {
+			t.Errorf("expected an error for layout %q", layout)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package rainbow
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/nerdwave-nick/rainbow/ansi"
+)
+
+type LevelStyle int
+
+const (
+	// the level name in the level color
+	LevelStyleText LevelStyle = iota
+	// the level name in reverse video, a block of the level color
+	LevelStyleReverse
+	// the level name in black and bold on a background of the level color
+	LevelStyleBadge
+)
+
+type LevelIcons int
+
+const (
+	LevelIconsNone LevelIcons = iota
+	// plain characters that every terminal can show
+	LevelIconsASCII
+	// needs a terminal and font with color emoji
+	LevelIconsEmoji
+	// needs a patched font from nerdfonts.com, there is no telling
+	// from the terminal if it has one
+	LevelIconsNerdFont
+)
+
+// icons for debug, info, warn and error
+var levelIcons = map[LevelIcons][4]string{
+	LevelIconsASCII:    {"*", "i", "!", "x"},
+	LevelIconsEmoji:    {"\U0001f41b", "\U0001f4a1", "\U0001f536", "\U0001f525"},
+	LevelIconsNerdFont: {"\ueaaf", "\uea74", "\uea6c", "\uea87"},
+}
+
+// LineTint colors whole lines to make severe records stand out
+type LineTint struct {
+	// records at or above Level are tinted
+	Level slog.Level
+	// Background puts the line on a background of the level color,
+	// instead of writing all of its text in the level color
+	Background bool
+}
+
+// levelIcon picks the icon of the set for levels in between as well
+func (h *TextHandler) levelIcon(level slog.Level) string {
+	icons, ok := levelIcons[h.levelIcons]
+	if !ok {
+		return ""
+	}
+	switch {
+	case level < slog.LevelInfo:
+		return icons[0]
+	case level < slog.LevelWarn:
+		return icons[1]
+	case level < slog.LevelError:
+		return icons[2]
+	}
+	return icons[3]
+}
+
+// fallbackIcons trades icons for ASCII ones when the terminal
+// can't show them, going by the locale and $TERM
+func fallbackIcons(icons LevelIcons) LevelIcons {
+	if icons == LevelIconsNone || icons == LevelIconsASCII {
+		return icons
+	}
+	if !unicodeTerminal() {
+		return LevelIconsASCII
+	}
+	return icons
+}
+
+// unicodeTerminal guesses if the terminal shows more than ASCII, the
+// linux console and dumb terminals don't, neither do non UTF-8 locales
+func unicodeTerminal() bool {
+	switch os.Getenv("TERM") {
+	case "dumb", "linux":
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		// Windows Terminal, the old console has no emoji
+		return os.Getenv("WT_SESSION") != ""
+	}
+	locale := os.Getenv("LC_ALL")
+	if locale == "" {
+		locale = os.Getenv("LC_CTYPE")
+	}
+	if locale == "" {
+		locale = os.Getenv("LANG")
+	}
+	locale = strings.ToLower(locale)
+	return strings.Contains(locale, "utf-8") || strings.Contains(locale, "utf8")
+}
+
+// badgeStyle turns the style of the level into the one of its block
+func (h *TextHandler) badgeStyle(col Style) Style {
+	if h.levelStyle == LevelStyleBadge && col.Fg.IsSet() {
+		return Style{Fg: BasicColor(0), Bg: col.Fg, Flags: col.Flags | FlagBold}
+	}
+	return col.With(FlagReverse)
+}
+
+// lineTint is the function restyling every part of a tinted line,
+// nil if the record isn't tinted
+func (h *TextHandler) lineTint(hs *handleState) func(Style) Style {
+	if h.tint == nil || !h.withColor || hs.Level < h.tint.Level {
+		return nil
+	}
+	color := h.style(StyleRequest{Element: ElementLevel, Level: hs.Level}, hs).Fg
+	if !color.IsSet() {
+		return nil
+	}
+	if !h.tint.Background {
+		return func(st Style) Style {
+			// blocks of their own, like badges, stay as they are
+			if st.Bg.IsSet() || st.Has(FlagReverse) {
+				return st
+			}
+			return st.WithFg(color)
+		}
+	}
+	return func(st Style) Style {
+		if st.Bg.IsSet() || st.Has(FlagReverse) {
+			return st
+		}
+		if st.Fg == color {
+			// would vanish into the background
+			st.Fg = BasicColor(0)
+		}
+		return st.WithBg(color)
+	}
+}
+
+// appendTinted writes the line with every style passed through tint,
+// a background is filled in up to the end of every line
+func (h *TextHandler) appendTinted(dst, line []byte, tint func(Style) Style) []byte {
+	if !h.tint.Background {
+		return ansi.Restyle(dst, line, tint)
+	}
+	// erasing the rest of a line fills it with the current background
+	line = bytes.ReplaceAll(line, []byte("\n"), []byte("\x1b[K\n"))
+	return ansi.Restyle(dst, append(line, "\x1b[K"...), tint)
+}
@@ -0,0 +1,102 @@
+package rainbow_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/nerdwave-nick/rainbow"
+)
+
+func TestRainbow_LevelStyle(t *testing.T) {
+	tests := []struct {
+		Options rainbow.Options
+		Term    string
+		Output  string
+	}{
+		{
+			Options: rainbow.Options{LevelStyle: rainbow.LevelStyleReverse},
+			Output:  "\x1b[7;31m ERR \x1b[0m m\x1b[0m\n",
+		},
+		{
+			Options: rainbow.Options{LevelStyle: rainbow.LevelStyleBadge, Layout: "{level} {msg}"},
+			Output:  "\x1b[1;30;41m ERR \x1b[0m\x1b[2;97m \x1b[0mm\x1b[0m\n",
+		},
+		{
+			// no color, no block
+			Options: rainbow.Options{LevelStyle: rainbow.LevelStyleBadge, NoColor: true},
+			Output:  "|ERR m\n",
+		},
+		{
+			Options: rainbow.Options{LevelIcons: rainbow.LevelIconsASCII, NoColor: true},
+			Output:  "|x ERR m\n",
+		},
+		{
+			Options: rainbow.Options{LevelIcons: rainbow.LevelIconsEmoji, NoColor: true, Layout: "{level:long} {msg}"},
+			Term:    "xterm-256color",
+			Output:  "\U0001f525 ERROR m\n",
+		},
+		{
+			Options: rainbow.Options{LevelIcons: rainbow.LevelIconsNerdFont, LevelStyle: rainbow.LevelStyleReverse},
+			Term:    "xterm-256color",
+			Output:  "\x1b[7;31m \uea87 ERR \x1b[0m m\x1b[0m\n",
+		},
+		{
+			// the linux console has no emoji
+			Options: rainbow.Options{LevelIcons: rainbow.LevelIconsEmoji, NoColor: true},
+			Term:    "linux",
+			Output:  "|x ERR m\n",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("level style test %d", i), func(t *testing.T) {
+			t.Setenv("TERM", tt.Term)
+			t.Setenv("LC_ALL", "en_US.UTF-8")
+			buffer := bytes.NewBuffer(make([]byte, 0))
+			h := rainbow.New(buffer, &tt.Options)
+			handleNoTime(t, h, slog.LevelError, "m")
+			if buffer.String() != tt.Output {
+				t.Errorf("output %q did not match the expected output %q", buffer.String(), tt.Output)
+			}
+		})
+	}
+}
+
+func TestRainbow_LineTint(t *testing.T) {
+	tests := []struct {
+		Level      slog.Level
+		Background bool
+		Output     string
+	}{
+		{
+			Level:  slog.LevelInfo,
+			Output: "\x1b[34m|INF \x1b[0mm\x1b[0m\x1b[2;97m \x1b[0m\x1b[2;3;97mn\x1b[0m\x1b[2;97m=\x1b[0m\x1b[33m1\x1b[0m\n",
+		},
+		{
+			Level:  slog.LevelWarn,
+			Output: "\x1b[33m|WRN m\x1b[0m\x1b[2;33m \x1b[0m\x1b[2;3;33mn\x1b[0m\x1b[2;33m=\x1b[0m\x1b[33m1\x1b[0m\n",
+		},
+		{
+			// the level and the value are yellow as well, so they turn black
+			Level:      slog.LevelWarn,
+			Background: true,
+			Output:     "\x1b[30;43m|WRN \x1b[0m\x1b[43mm\x1b[0m\x1b[2;97;43m \x1b[0m\x1b[2;3;97;43mn\x1b[0m\x1b[2;97;43m=\x1b[0m\x1b[30;43m1\x1b[0m\x1b[43m\x1b[K\x1b[0m\n",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("line tint test %d", i), func(t *testing.T) {
+			buffer := bytes.NewBuffer(make([]byte, 0))
+			h := rainbow.New(buffer, &rainbow.Options{
+				AttrLayout: rainbow.AttrLayoutSingle,
+				LineTint:   &rainbow.LineTint{Level: slog.LevelWarn, Background: tt.Background},
+			})
+			handleNoTime(t, h, tt.Level, "m", slog.Int("n", 1))
+			if buffer.String() != tt.Output {
+				t.Errorf("output %q did not match the expected output %q", buffer.String(), tt.Output)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package rainbow
+
+import (
+	"log/slog"
+	"net/url"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// LinkRule makes the values of matching keys links,
+// like a trace id linking to the page of the trace
+type LinkRule struct {
+	// Key is a key name or glob, matched like NumberFormat.Keys
+	Key string `json:"key"`
+	// URL is the target, {value} is replaced with the escaped
+	// value, e.g. "http://localhost:16686/trace/{value}"
+	URL string `json:"url"`
+}
+
+type linkRule struct {
+	key keyPattern
+	url string
+}
+
+type linker struct {
+	// host of file:// links
+	host  string
+	rules []linkRule
+}
+
+// compileLinks is nil when links are off, or the output
+// doesn't look like a terminal that can show them
+func compileLinks(enabled, withColor bool, rules []LinkRule) *linker {
+	if !enabled || !withColor || !hyperlinkTerminal() {
+		return nil
+	}
+	host, _ := os.Hostname()
+	l := &linker{host: host}
+	for _, r := range rules {
+		l.rules = append(l.rules, linkRule{key: compileKeyPatterns([]string{r.Key})[0], url: r.URL})
+	}
+	return l
+}
+
+// hyperlinkTerminal guesses if the terminal shows OSC 8 links from
+// the variables terminals set, FORCE_HYPERLINK=1 or 0 decides for it
+func hyperlinkTerminal() bool {
+	if force, ok := os.LookupEnv("FORCE_HYPERLINK"); ok {
+		return force != "0"
+	}
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty", "Hyper", "Tabby", "rio":
+		return true
+	}
+	if os.Getenv("WT_SESSION") != "" || os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("KONSOLE_VERSION") != "" {
+		return true
+	}
+	// GNOME Terminal, Tilix and the other terminals built on VTE 0.50 or later
+	if v, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && v >= 5000 {
+		return true
+	}
+	if runtime.GOOS == "windows" {
+		return false
+	}
+	term := os.Getenv("TERM")
+	return term == "xterm-kitty" || term == "alacritty" || strings.HasPrefix(term, "foot")
+}
+
+// ruleTarget is the link of a value, if a rule matches its key
+func (l *linker) ruleTarget(groups []string, a slog.Attr) string {
+	if l == nil {
+		return ""
+	}
+	for _, r := range l.rules {
+		if r.key.match(groups, a.Key) {
+			return strings.ReplaceAll(r.url, "{value}", url.PathEscape(a.Value.String()))
+		}
+	}
+	return ""
+}
+
+// tokenTarget is the link of a highlighted token,
+// URLs link to themselves and absolute paths to the file
+func (l *linker) tokenTarget(kind tokenKind, token string) string {
+	if l == nil {
+		return ""
+	}
+	switch {
+	case kind == tokenURL:
+		return token
+	case kind == tokenPath && strings.HasPrefix(token, "/"):
+		return l.fileTarget(trimLineNumbers(token))
+	}
+	return ""
+}
+
+func (l *linker) fileTarget(path string) string {
+	return (&url.URL{Scheme: "file", Host: l.host, Path: path}).String()
+}
+
+// trimLineNumbers leaves the :line and :line:column off of a path
+func trimLineNumbers(path string) string {
+	for range 2 {
+		i := strings.LastIndexByte(path, ':')
+		if i == -1 {
+			break
+		}
+		if _, err := strconv.Atoi(path[i+1:]); err != nil {
+			break
+		}
+		path = path[:i]
+	}
+	return path
+}
+
+func appendLinkStart(buf []byte, target string) []byte {
+	buf = append(buf, "\x1b]8;;"...)
+	buf = append(buf, target...)
+	return append(buf, "\x1b\\"...)
+}
+
+func appendLinkEnd(buf []byte) []byte {
+	return append(buf, "\x1b]8;;\x1b\\"...)
+}
@@ -0,0 +1,83 @@
+package rainbow_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/nerdwave-nick/rainbow"
+)
+
+func TestRainbow_Hyperlinks(t *testing.T) {
+	host, _ := os.Hostname()
+	link := func(target, text string) string {
+		return "\x1b]8;;" + target + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+	}
+	tests := []struct {
+		Force    string
+		NoColor  bool
+		Template rainbow.MessageTemplate
+		Message  string
+		Attrs    []slog.Attr
+		Output   string
+	}{
+		{
+			Force:   "1",
+			Message: "see https://example.com/a, or /var/log/app.log:12",
+			Output:  "<m>see " + link("https://example.com/a", "https://example.com/a") + ", or " + link("file://"+host+"/var/log/app.log", "/var/log/app.log:12") + "<ro>\n",
+		},
+		{
+			Force:   "1",
+			Message: "m",
+			Attrs:   []slog.Attr{slog.String("trace_id", "ab 12"), slog.String("url", "at https://example.com")},
+			Output: "<m>m<ro><so> <ro><kd>trace_id<ro><so>=<ro>" + link("http://localhost:16686/trace/ab%2012", "<vs>\"ab 12\"<ro>") +
+				"<so> <ro><kd>url<ro><so>=<ro><vs>\"at " + link("https://example.com", "https://example.com") + "\"<ro>\n",
+		},
+		{
+			// the value is a link already, the message doesn't link it again
+			Force:    "1",
+			Template: rainbow.MessageTemplateConsume,
+			Message:  "trace {trace_id}",
+			Attrs:    []slog.Attr{slog.String("trace_id", "https://example.com")},
+			Output:   "<m>trace " + link("http://localhost:16686/trace/https:%2F%2Fexample.com", "<vs>https://example.com<ro>") + "<m><ro>\n",
+		},
+		{
+			// control bytes aren't part of the url
+			Force:   "1",
+			Message: "see https://example.com/a\ab https://example.com/c\x7f",
+			Output:  "<m>see " + link("https://example.com/a", "https://example.com/a") + "\ab " + link("https://example.com/c", "https://example.com/c") + "\x7f<ro>\n",
+		},
+		{
+			Force:   "0",
+			Message: "see https://example.com",
+			Output:  "<m>see https://example.com<ro>\n",
+		},
+		{
+			Force:   "1",
+			NoColor: true,
+			Message: "see https://example.com",
+			Output:  "see https://example.com\n",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("hyperlink test %d", i), func(t *testing.T) {
+			t.Setenv("FORCE_HYPERLINK", tt.Force)
+			buffer := bytes.NewBuffer(make([]byte, 0))
+			o := opts
+			o.NoColor = tt.NoColor
+			o.Layout = "{msg}{attrs}"
+			o.AttrLayout = rainbow.AttrLayoutSingle
+			o.Hyperlinks = true
+			o.LinkRules = []rainbow.LinkRule{{Key: "trace_id", URL: "http://localhost:16686/trace/{value}"}}
+			o.MessageTemplate = tt.Template
+			h := rainbow.New(buffer, &o)
+			handleNoTime(t, h, slog.LevelInfo, tt.Message, tt.Attrs...)
+			if buffer.String() != tt.Output {
+				t.Errorf("output %q did not match the expected output %q", buffer.String(), tt.Output)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package rainbow
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+)
+
+// Output is a writer and the options of the handler writing to it
+type Output struct {
+	Writer  io.Writer
+	Options *Options
+}
+
+// MultiHandler writes every record to a handler for each of its
+// outputs, like colors on the terminal and plain text in a file
+type MultiHandler struct {
+	handlers []slog.Handler
+}
+
+// Multi makes a handler writing to all outputs, each with
+// its own options and level
+func Multi(outputs ...Output) *MultiHandler {
+	m := &MultiHandler{handlers: make([]slog.Handler, len(outputs))}
+	for i, o := range outputs {
+		m.handlers[i] = New(o.Writer, o.Options)
+	}
+	return m
+}
+
+func (m *MultiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m.handlers {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle writes the record to every output it's enabled for. An output
+// failing doesn't stop the others, the errors of all are returned.
+func (m *MultiHandler) Handle(ctx context.Context, r slog.Record) error {
+	resolved := false
+	var errs []error
+	for _, h := range m.handlers {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+		if !resolved {
+			// once here, instead of once for every output
+			r = resolveRecord(r)
+			resolved = true
+		}
+		if err := h.Handle(ctx, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *MultiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return m
+	}
+	resolved := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		resolved[i] = resolveAttr(a)
+	}
+	m2 := &MultiHandler{handlers: make([]slog.Handler, len(m.handlers))}
+	// made once for all outputs, each keeps the way it writes them in it
+	var ca *contextAttrs
+	made := false
+	for i, h := range m.handlers {
+		s, ok := h.(contextSharer)
+		if !ok {
+			m2.handlers[i] = h.WithAttrs(resolved)
+			continue
+		}
+		if !made {
+			ca = s.newContext(resolved)
+			made = true
+		}
+		m2.handlers[i] = s.withContext(ca)
+	}
+	return m2
+}
+
+func (m *MultiHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return m
+	}
+	m2 := &MultiHandler{handlers: make([]slog.Handler, len(m.handlers))}
+	for i, h := range m.handlers {
+		m2.handlers[i] = h.WithGroup(name)
+	}
+	return m2
+}
+
+// Flush flushes the outputs with BatchOptions, see TextHandler.Flush
+func (m *MultiHandler) Flush() error {
+	var errs []error
+	for _, h := range m.handlers {
+		if f, ok := h.(Flusher); ok {
+			if err := f.Flush(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every output, see TextHandler.Close
+func (m *MultiHandler) Close() error {
+	var errs []error
+	for _, h := range m.handlers {
+		if c, ok := h.(interface{ Close() error }); ok {
+			if err := c.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// contextSharer is a handler that can take the attrs of a WithAttrs
+// call made by another one, so the outputs of a Multi share them.
+// The outputs of a Multi always have the same groups.
+type contextSharer interface {
+	// newContext makes the attrs of a WithAttrs call,
+	// nil if there are none left to write
+	newContext(attrs []slog.Attr) *contextAttrs
+	// withContext is WithAttrs with attrs from newContext
+	withContext(ca *contextAttrs) slog.Handler
+}
+
+// resolveRecord resolves the LogValuers of a record, r itself
+// is returned if it has none, which is the usual case
+func resolveRecord(r slog.Record) slog.Record {
+	hasValuers := false
+	r.Attrs(func(a slog.Attr) bool {
+		hasValuers = needsResolve(a.Value)
+		return !hasValuers
+	})
+	if !hasValuers {
+		return r
+	}
+	r2 := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		r2.AddAttrs(resolveAttr(a))
+		return true
+	})
+	return r2
+}
+
+func needsResolve(v slog.Value) bool {
+	switch v.Kind() {
+	case slog.KindLogValuer:
+		return true
+	case slog.KindGroup:
+		for _, a := range v.Group() {
+			if needsResolve(a.Value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveAttr resolves the value of a, and the ones in its groups
+func resolveAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup || !needsResolve(a.Value) {
+		return a
+	}
+	group := a.Value.Group()
+	resolved := make([]slog.Attr, len(group))
+	for i, c := range group {
+		resolved[i] = resolveAttr(c)
+	}
+	a.Value = slog.GroupValue(resolved...)
+	return a
+}
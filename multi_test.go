@@ -0,0 +1,88 @@
+package rainbow_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nerdwave-nick/rainbow"
+	"github.com/nerdwave-nick/rainbow/ansi"
+)
+
+// countingValuer counts how often it is resolved
+type countingValuer struct {
+	count *atomic.Int32
+}
+
+func (v countingValuer) LogValue() slog.Value {
+	v.count.Add(1)
+	return slog.StringValue("resolved")
+}
+
+func TestRainbow_Multi(t *testing.T) {
+	terminal := bytes.NewBuffer(make([]byte, 0))
+	file := bytes.NewBuffer(make([]byte, 0))
+	var h slog.Handler = rainbow.Multi(
+		rainbow.Output{Writer: terminal, Options: &rainbow.Options{Level: slog.LevelWarn, Layout: "{level} {msg}{attrs}"}},
+		rainbow.Output{Writer: file, Options: &rainbow.Options{NoColor: true, Level: slog.LevelDebug, Layout: "{level} {msg}{attrs}"}},
+	)
+	count := &atomic.Int32{}
+	h = h.WithAttrs([]slog.Attr{slog.Int("a", 1)}).WithGroup("g")
+
+	if !h.Enabled(context.Background(), slog.LevelDebug) {
+		t.Errorf("debug records are not enabled for the file")
+	}
+	handleNoTime(t, h, slog.LevelDebug, "d", slog.Any("v", countingValuer{count}))
+	handleNoTime(t, h, slog.LevelError, "e", slog.Group("gr", slog.Any("v", countingValuer{count})))
+
+	expected := "DBG d\n\ta=1\n\tg.v=\"resolved\"\nERR e\n\ta=1\n\tg.gr.v=\"resolved\"\n"
+	if file.String() != expected {
+		t.Errorf("output %q did not match the expected output %q", file.String(), expected)
+	}
+	// the terminal gets the error only, the same apart from the colors
+	if plain := ansi.Strip(terminal.String()); plain != expected[strings.Index(expected, "ERR"):] {
+		t.Errorf("output %q did not match the expected output %q", plain, expected[strings.Index(expected, "ERR"):])
+	}
+	if count.Load() != 2 {
+		t.Errorf("values were resolved %d times instead of once per record", count.Load())
+	}
+}
+
+func TestRainbow_MultiErrors(t *testing.T) {
+	file := bytes.NewBuffer(make([]byte, 0))
+	h := rainbow.Multi(
+		rainbow.Output{Writer: failingWriter{}},
+		rainbow.Output{Writer: file, Options: &rainbow.Options{NoColor: true, Layout: "{msg}"}},
+	)
+	err := h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "m", 0))
+	if err == nil || err.Error() != "broken pipe" {
+		t.Errorf("handle returned %v instead of the write error", err)
+	}
+	if file.String() != "m\n" {
+		t.Errorf("output %q did not match the expected output %q", file.String(), "m\n")
+	}
+}
+
+func TestRainbow_MultiSharedContext(t *testing.T) {
+	terminal := bytes.NewBuffer(make([]byte, 0))
+	file := bytes.NewBuffer(make([]byte, 0))
+	h := rainbow.Multi(
+		rainbow.Output{Writer: terminal, Options: &rainbow.Options{Layout: "{msg}{attrs}"}},
+		rainbow.Output{Writer: file, Options: &rainbow.Options{NoColor: true, Layout: "{msg}{attrs}"}},
+	).WithAttrs([]slog.Attr{slog.Int("a", 1)})
+	handleNoTime(t, h, slog.LevelInfo, "one")
+	handleNoTime(t, h, slog.LevelInfo, "two")
+
+	// the outputs share the attrs, but not the way they are written
+	expected := "one\n\ta=1\ntwo\n\ta=1\n"
+	if file.String() != expected {
+		t.Errorf("output %q did not match the expected output %q", file.String(), expected)
+	}
+	if plain := ansi.Strip(terminal.String()); plain != expected || plain == terminal.String() {
+		t.Errorf("output %q did not match the expected output %q", terminal.String(), expected)
+	}
+}
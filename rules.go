@@ -0,0 +1,232 @@
+package rainbow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type RuleOp string
+
+const (
+	RuleLess         RuleOp = "<"
+	RuleLessEqual    RuleOp = "<="
+	RuleGreater      RuleOp = ">"
+	RuleGreaterEqual RuleOp = ">="
+	RuleEqual        RuleOp = "=="
+	RuleNotEqual     RuleOp = "!="
+	// Value is a regular expression matched against strings and error texts
+	RuleMatch RuleOp = "=~"
+	// Value is an inclusive range like "400..499" or "100ms..1s"
+	RuleBetween RuleOp = "in"
+)
+
+// ColorRule colors the value of matching keys with Mod when
+// the value satisfies Op and Value. Numbers are compared with numbers,
+// durations with durations like "500ms", bools with "true"/"false"
+// and strings with the text itself.
+//
+// A rule that fails Validate never matches.
+type ColorRule struct {
+	// Key is a key name or glob, matched like NumberFormat.Keys
+	Key   string  `json:"key"`
+	Op    RuleOp  `json:"op"`
+	Value string  `json:"value"`
+	Mod   AnsiMod `json:"mod"`
+}
+
+type colorRule struct {
+	key   keyPattern
+	op    RuleOp
+	style Style
+	text  string
+
+	isNum  bool
+	num    float64
+	numHi  float64
+	isDur  bool
+	dur    time.Duration
+	durHi  time.Duration
+	isBool bool
+	bool   bool
+	re     *regexp.Regexp
+}
+
+func (r ColorRule) compile() (colorRule, error) {
+	cr := colorRule{
+		key:   compileKeyPatterns([]string{r.Key})[0],
+		op:    r.Op,
+		style: r.Mod.Style(),
+		text:  r.Value,
+	}
+	if r.Key == "" {
+		return cr, errors.New("rainbow: color rule without key")
+	}
+	switch r.Op {
+	case RuleMatch:
+		re, err := regexp.Compile(r.Value)
+		if err != nil {
+			return cr, fmt.Errorf("rainbow: color rule for %q: %w", r.Key, err)
+		}
+		cr.re = re
+	case RuleBetween:
+		lo, hi, ok := strings.Cut(r.Value, "..")
+		if !ok {
+			return cr, fmt.Errorf("rainbow: color rule for %q: range %q is not of the form lo..hi", r.Key, r.Value)
+		}
+		if nlo, err := strconv.ParseFloat(lo, 64); err == nil {
+			if nhi, err := strconv.ParseFloat(hi, 64); err == nil {
+				cr.isNum, cr.num, cr.numHi = true, nlo, nhi
+			}
+		}
+		if dlo, err := time.ParseDuration(lo); err == nil {
+			if dhi, err := time.ParseDuration(hi); err == nil {
+				cr.isDur, cr.dur, cr.durHi = true, dlo, dhi
+			}
+		}
+		if !cr.isNum && !cr.isDur {
+			return cr, fmt.Errorf("rainbow: color rule for %q: range %q needs numbers or durations", r.Key, r.Value)
+		}
+	case RuleLess, RuleLessEqual, RuleGreater, RuleGreaterEqual, RuleEqual, RuleNotEqual:
+		if n, err := strconv.ParseFloat(r.Value, 64); err == nil {
+			cr.isNum, cr.num = true, n
+		}
+		if d, err := time.ParseDuration(r.Value); err == nil {
+			cr.isDur, cr.dur = true, d
+		}
+		if b, err := strconv.ParseBool(r.Value); err == nil {
+			cr.isBool, cr.bool = true, b
+		}
+		if r.Op != RuleEqual && r.Op != RuleNotEqual && !cr.isNum && !cr.isDur {
+			return cr, fmt.Errorf("rainbow: color rule for %q: %s needs a number or duration, got %q", r.Key, r.Op, r.Value)
+		}
+	default:
+		return cr, fmt.Errorf("rainbow: color rule for %q: unknown op %q", r.Key, r.Op)
+	}
+	return cr, nil
+}
+
+// Validate reports whether the rule can be compiled
+func (r ColorRule) Validate() error {
+	_, err := r.compile()
+	return err
+}
+
+func (r *ColorRule) UnmarshalJSON(data []byte) error {
+	// plain alias to not recurse into this method
+	type colorRule ColorRule
+	if err := json.Unmarshal(data, (*colorRule)(r)); err != nil {
+		return err
+	}
+	return r.Validate()
+}
+
+func compileColorRules(rules []ColorRule) []colorRule {
+	compiled := make([]colorRule, 0, len(rules))
+	for _, r := range rules {
+		cr, err := r.compile()
+		if err != nil {
+			continue
+		}
+		compiled = append(compiled, cr)
+	}
+	return compiled
+}
+
+func (cr *colorRule) matches(v slog.Value) bool {
+	switch v.Kind() {
+	case slog.KindInt64:
+		return cr.isNum && cr.compareNum(float64(v.Int64()))
+	case slog.KindUint64:
+		return cr.isNum && cr.compareNum(float64(v.Uint64()))
+	case slog.KindFloat64:
+		return cr.isNum && cr.compareNum(v.Float64())
+	case slog.KindDuration:
+		return cr.isDur && cr.compareDur(v.Duration())
+	case slog.KindBool:
+		if !cr.isBool {
+			return false
+		}
+		switch cr.op {
+		case RuleEqual:
+			return v.Bool() == cr.bool
+		case RuleNotEqual:
+			return v.Bool() != cr.bool
+		}
+		return false
+	case slog.KindString:
+		return cr.compareString(v.String())
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return cr.compareString(err.Error())
+		}
+	}
+	return false
+}
+
+func (cr *colorRule) compareNum(n float64) bool {
+	switch cr.op {
+	case RuleLess:
+		return n < cr.num
+	case RuleLessEqual:
+		return n <= cr.num
+	case RuleGreater:
+		return n > cr.num
+	case RuleGreaterEqual:
+		return n >= cr.num
+	case RuleEqual:
+		return n == cr.num
+	case RuleNotEqual:
+		return n != cr.num
+	case RuleBetween:
+		return n >= cr.num && n <= cr.numHi
+	}
+	return false
+}
+
+func (cr *colorRule) compareDur(d time.Duration) bool {
+	switch cr.op {
+	case RuleLess:
+		return d < cr.dur
+	case RuleLessEqual:
+		return d <= cr.dur
+	case RuleGreater:
+		return d > cr.dur
+	case RuleGreaterEqual:
+		return d >= cr.dur
+	case RuleEqual:
+		return d == cr.dur
+	case RuleNotEqual:
+		return d != cr.dur
+	case RuleBetween:
+		return d >= cr.dur && d <= cr.durHi
+	}
+	return false
+}
+
+func (cr *colorRule) compareString(s string) bool {
+	switch cr.op {
+	case RuleEqual:
+		return s == cr.text
+	case RuleNotEqual:
+		return s != cr.text
+	case RuleMatch:
+		return cr.re.MatchString(s)
+	}
+	return false
+}
+
+func (s *overrideStyler) ruleColor(groups []string, key string, v slog.Value) (Style, bool) {
+	for i := range s.colorRules {
+		cr := &s.colorRules[i]
+		if cr.key.match(groups, key) && cr.matches(v) {
+			return cr.style, true
+		}
+	}
+	return Style{}, false
+}
@@ -0,0 +1,94 @@
+package rainbow_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/nerdwave-nick/rainbow"
+)
+
+var ruleOpts = rainbow.Options{
+	NoColor:              false,
+	MessageAttrSeparator: " ",
+	AttrAttrSeparator:    " ",
+	ResetOverride:        "<ro>",
+	SymbolOverride:       "<so>",
+	LevelOverrides:       opts.LevelOverrides,
+	ValueOverrides:       opts.ValueOverrides,
+	SpecialOverrides:     opts.SpecialOverrides,
+	KeyOverrides:         &rainbow.KeyColorOverrides{},
+	ColorRules: []rainbow.ColorRule{
+		{Key: "latency", Op: rainbow.RuleGreater, Value: "500ms", Mod: "<slow>"},
+		{Key: "status", Op: rainbow.RuleGreaterEqual, Value: "500", Mod: "<5xx>"},
+		{Key: "status", Op: rainbow.RuleBetween, Value: "400..499", Mod: "<4xx>"},
+		{Key: "ok", Op: rainbow.RuleEqual, Value: "false", Mod: "<nok>"},
+		{Key: "http.method", Op: rainbow.RuleMatch, Value: "^(POST|PUT)$", Mod: "<write>"},
+		{Key: "err", Op: rainbow.RuleMatch, Value: "timeout", Mod: "<timeout>"},
+		{Key: "broken", Op: rainbow.RuleMatch, Value: "(", Mod: "<never>"},
+	},
+}
+
+func TestRainbow_ColorRules(t *testing.T) {
+	tests := []struct {
+		Attr   slog.Attr
+		Output string
+	}{
+		{Attr: slog.Duration("latency", time.Second), Output: "latency<ro><so>=<ro><slow>1s<ro>"},
+		{Attr: slog.Duration("latency", time.Millisecond), Output: "latency<ro><so>=<ro><vd>1ms<ro>"},
+		{Attr: slog.Int("status", 503), Output: "status<ro><so>=<ro><5xx>503<ro>"},
+		{Attr: slog.Int("status", 404), Output: "status<ro><so>=<ro><4xx>404<ro>"},
+		{Attr: slog.Uint64("status", 200), Output: "status<ro><so>=<ro><vu>200<ro>"},
+		{Attr: slog.Bool("ok", false), Output: "ok<ro><so>=<ro><nok>false<ro>"},
+		{Attr: slog.Bool("ok", true), Output: "ok<ro><so>=<ro><vb>true<ro>"},
+		{Attr: slog.Group("http", slog.String("method", "POST")), Output: "http<ro><so>.<ro>method<ro><so>=<ro><write>\"POST\"<ro>"},
+		{Attr: slog.String("method", "POST"), Output: "method<ro><so>=<ro><vs>\"POST\"<ro>"},
+		{Attr: slog.Any("err", errors.New("read timeout")), Output: "err<ro><so>=<ro><timeout>read timeout<ro>"},
+		{Attr: slog.String("broken", "("), Output: "broken<ro><so>=<ro><vs>\"(\"<ro>"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("color rule test %d", i), func(t *testing.T) {
+			buffer := bytes.NewBuffer(make([]byte, 0))
+			h := rainbow.New(buffer, &ruleOpts)
+			r := slog.NewRecord(time.Time{}, slog.LevelInfo, "m", 0)
+			r.AddAttrs(tt.Attr)
+			if err := h.Handle(context.Background(), r); err != nil {
+				t.Fatal(err)
+			}
+			expected := "<li>|INF <ro><m>m<ro><so> <ro>" + tt.Output + "\n"
+			if buffer.String() != expected {
+				t.Errorf("output %q did not match the expected output %q", buffer.String(), expected)
+			}
+		})
+	}
+}
+
+func TestRainbow_ColorRulesJSON(t *testing.T) {
+	var rules []rainbow.ColorRule
+	err := json.Unmarshal([]byte(`[{"key": "status", "op": ">=", "value": "500", "mod": "bold red"}]`), &rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := rainbow.ColorRule{Key: "status", Op: rainbow.RuleGreaterEqual, Value: "500", Mod: rainbow.Mod(rainbow.Fmt.Bold, rainbow.Fg.Red)}
+	if len(rules) != 1 || rules[0] != expected {
+		t.Errorf("rules %q did not match the expected rule %q", rules, expected)
+	}
+
+	invalid := []string{
+		`[{"key": "status", "op": ">=", "value": "many", "mod": "red"}]`,
+		`[{"key": "status", "op": "in", "value": "400", "mod": "red"}]`,
+		`[{"key": "status", "op": "~", "value": "400", "mod": "red"}]`,
+		`[{"key": "status", "op": "==", "value": "400", "mod": "purple"}]`,
+	}
+	for _, in := range invalid {
+		if err := json.Unmarshal([]byte(in), &rules); err == nil {
+			t.Errorf("expected an error for %s", in)
+		}
+	}
+}
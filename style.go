@@ -0,0 +1,52 @@
+package rainbow
+
+import (
+	"github.com/nerdwave-nick/rainbow/ansi"
+)
+
+// the structured style types live in the ansi package,
+// so they can be used when reading rainbow output as well
+type (
+	Style      = ansi.Style
+	Color      = ansi.Color
+	StyleFlags = ansi.StyleFlags
+)
+
+const (
+	FlagBold      = ansi.FlagBold
+	FlagFaint     = ansi.FlagFaint
+	FlagItalic    = ansi.FlagItalic
+	FlagUnderline = ansi.FlagUnderline
+	FlagBlink     = ansi.FlagBlink
+	FlagReverse   = ansi.FlagReverse
+	FlagStrike    = ansi.FlagStrike
+)
+
+func BasicColor(n uint8) Color {
+	return ansi.BasicColor(n)
+}
+
+func IndexedColor(n uint8) Color {
+	return ansi.IndexedColor(n)
+}
+
+func RGBColor(r, g, b uint8) Color {
+	return ansi.RGBColor(r, g, b)
+}
+
+// Style parses the mod into a structured style, see ParseStyle
+func (m AnsiMod) Style() Style {
+	return ansi.ParseStyle(string(m))
+}
+
+// ParseStyle turns a mod made of SGR sequences into a Style.
+// Anything that isn't an SGR sequence is kept as it is,
+// so every AnsiMod survives the trip.
+func ParseStyle(m AnsiMod) Style {
+	return ansi.ParseStyle(string(m))
+}
+
+// ModOf renders a style back into a mod
+func ModOf(s Style) AnsiMod {
+	return AnsiMod(s.String())
+}
@@ -0,0 +1,227 @@
+package rainbow
+
+import (
+	"log/slog"
+)
+
+// Element is the part of a record a style is asked for
+type Element int
+
+const (
+	ElementTime Element = iota
+	ElementLevel
+	ElementMessage
+	ElementKey
+	ElementGroup
+	ElementValue
+	// ElementSource is the file and line a record was logged from
+	ElementSource
+)
+
+// StyleRequest describes the element that is about to be written
+type StyleRequest struct {
+	Element Element
+	// Key is the attribute key for keys and values,
+	// and the group name for groups
+	Key string
+	// Groups are the names of the groups the element is nested in,
+	// both from WithGroup and from inline slog.Group values
+	Groups []string
+	// Value is the attribute value for keys and values,
+	// the record time for times and file:line for sources
+	Value slog.Value
+	// Level of the record being written
+	Level slog.Level
+}
+
+// Styler decides the style of every element the handler writes.
+// The zero Style means the styler has no opinion about the element.
+type Styler interface {
+	Style(req StyleRequest) Style
+}
+
+type StylerFunc func(req StyleRequest) Style
+
+func (f StylerFunc) Style(req StyleRequest) Style {
+	return f(req)
+}
+
+// Compose layers stylers on top of each other, later ones
+// are merged over earlier ones, see Style.Merge.
+func Compose(stylers ...Styler) Styler {
+	return composedStyler(stylers)
+}
+
+type composedStyler []Styler
+
+func (cs composedStyler) Style(req StyleRequest) Style {
+	var st Style
+	for _, s := range cs {
+		if s == nil {
+			continue
+		}
+		st = st.Merge(s.Style(req))
+	}
+	return st
+}
+
+// overrideStyler is the styler built from the override structs,
+// color rules and hash colors of the options
+type overrideStyler struct {
+	levels     [4]Style
+	time       Style
+	message    Style
+	source     Style
+	keyDefault Style
+	keys       map[string]Style
+	groups     map[string]Style
+	values     [slog.KindLogValuer + 1]Style
+	error      Style
+	negative   Style
+
+	colorRules []colorRule
+	hashColors *hashColors
+}
+
+// DefaultStyler returns the styler the handler uses for the given options,
+// ignoring opts.Styler. Useful as the bottom layer of Compose.
+func DefaultStyler(opts *Options) Styler {
+	if opts == nil {
+		opts = &Options{}
+	}
+	return newOverrideStyler(opts)
+}
+
+func newOverrideStyler(opts *Options) *overrideStyler {
+	levelColors := getOrDefaultLevelColorOverrides(opts.LevelOverrides)
+	valueColors := getOrDefaultValueColorOverrides(opts.ValueOverrides)
+	keyColors := getOrDefaultKeyColorOverrides(opts.KeyOverrides)
+	specialColors := getOrDefaultSpecialOverrides(opts.SpecialOverrides)
+
+	s := &overrideStyler{
+		levels: [4]Style{
+			levelColors.Debug.Style(),
+			levelColors.Info.Style(),
+			levelColors.Warning.Style(),
+			levelColors.Error.Style(),
+		},
+		time:       specialColors.Time.Style(),
+		message:    specialColors.Message.Style(),
+		source:     specialColors.Source.Style(),
+		keyDefault: keyColors.Default.Style(),
+		keys:       make(map[string]Style, len(keyColors.KeyMap)),
+		groups:     make(map[string]Style, len(keyColors.GroupMap)),
+		error:      valueColors.Error.Style(),
+		negative:   valueColors.Negative.Style(),
+		colorRules: compileColorRules(opts.ColorRules),
+		hashColors: compileHashColors(opts.HashColors),
+	}
+	for k, mod := range keyColors.KeyMap {
+		s.keys[k] = mod.Style()
+	}
+	for k, mod := range keyColors.GroupMap {
+		s.groups[k] = mod.Style()
+	}
+	s.values[slog.KindAny] = valueColors.Any.Style()
+	s.values[slog.KindBool] = valueColors.Bool.Style()
+	s.values[slog.KindDuration] = valueColors.Duration.Style()
+	s.values[slog.KindFloat64] = valueColors.Float.Style()
+	s.values[slog.KindInt64] = valueColors.Int.Style()
+	s.values[slog.KindString] = valueColors.String.Style()
+	s.values[slog.KindTime] = valueColors.Time.Style()
+	s.values[slog.KindUint64] = valueColors.Uint.Style()
+	return s
+}
+
+func (s *overrideStyler) Style(req StyleRequest) Style {
+	switch req.Element {
+	case ElementTime:
+		return s.time
+	case ElementMessage:
+		return s.message
+	case ElementSource:
+		return s.source
+	case ElementLevel:
+		switch req.Level {
+		case slog.LevelDebug:
+			return s.levels[0]
+		case slog.LevelInfo:
+			return s.levels[1]
+		case slog.LevelWarn:
+			return s.levels[2]
+		case slog.LevelError:
+			return s.levels[3]
+		}
+	case ElementKey:
+		return s.keyColor(req.Key)
+	case ElementGroup:
+		return s.groupColor(req.Key)
+	case ElementValue:
+		return s.valueColor(req.Groups, req.Key, req.Value)
+	}
+	return Style{}
+}
+
+func (s *overrideStyler) keyColor(key string) Style {
+	if st, ok := s.keys[key]; ok {
+		return st
+	}
+	if s.hashColors != nil && s.hashColors.keys {
+		return s.hashColors.pick(key)
+	}
+	return s.keyDefault
+}
+
+func (s *overrideStyler) groupColor(name string) Style {
+	if st, ok := s.groups[name]; ok {
+		return st
+	}
+	if s.hashColors != nil && s.hashColors.keys {
+		return s.hashColors.pick(name)
+	}
+	return s.keyDefault
+}
+
+// valueColor picks the style of a value, the first matching
+// color rule wins over hash colors and the styles by kind
+func (s *overrideStyler) valueColor(groups []string, key string, v slog.Value) Style {
+	if st, ok := s.ruleColor(groups, key, v); ok {
+		return st
+	}
+	if st, ok := s.hashValueColor(groups, key, v); ok {
+		return st
+	}
+	negative := false
+	switch v.Kind() {
+	case slog.KindInt64:
+		negative = v.Int64() < 0
+	case slog.KindFloat64:
+		negative = v.Float64() < 0
+	case slog.KindDuration:
+		negative = v.Duration() < 0
+	case slog.KindAny:
+		if _, ok := v.Any().(error); ok {
+			return s.error
+		}
+	}
+	if negative && !s.negative.IsZero() {
+		return s.negative
+	}
+	return s.values[v.Kind()]
+}
+
+// style asks the configured stylers for the style of an element,
+// the custom styler is merged over the defaults
+func (h *TextHandler) style(req StyleRequest, hs *handleState) Style {
+	if !h.withColor {
+		return Style{}
+	}
+	st := h.defaults.Style(req)
+	if !hs.Tint.IsZero() && (req.Element == ElementTime || req.Element == ElementLevel) {
+		st = hs.Tint
+	}
+	if h.styler != nil {
+		st = st.Merge(h.styler.Style(req))
+	}
+	return st
+}
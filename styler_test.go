@@ -0,0 +1,72 @@
+package rainbow_test
+
+import (
+	"bytes"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/nerdwave-nick/rainbow"
+)
+
+func TestRainbow_Styler(t *testing.T) {
+	opts := opts
+	opts.MessageAttrSeparator = " "
+	opts.AttrAttrSeparator = " "
+	opts.Styler = rainbow.StylerFunc(func(req rainbow.StyleRequest) rainbow.Style {
+		switch {
+		case req.Element == rainbow.ElementKey && slices.Equal(req.Groups, []string{"wg", "http"}):
+			return rainbow.AnsiMod("<http-key>").Style()
+		case req.Element == rainbow.ElementValue && req.Level >= slog.LevelError:
+			return rainbow.AnsiMod("<loud>").Style()
+		case req.Element == rainbow.ElementGroup && req.Level >= slog.LevelError:
+			return rainbow.AnsiMod("<loud-group>").Style()
+		}
+		return rainbow.Style{}
+	})
+
+	buffer := bytes.NewBuffer(make([]byte, 0))
+	h := rainbow.New(buffer, &opts).WithGroup("wg").WithAttrs([]slog.Attr{slog.Int("a", 1)})
+
+	handleNoTime(t, h, slog.LevelInfo, "m", slog.Group("http", slog.String("method", "GET")))
+	handleNoTime(t, h, slog.LevelError, "m", slog.Group("http", slog.String("method", "GET")))
+
+	expected := "<li>|INF <ro><m>m<ro><so> <ro>" +
+		"<kd>wg<ro><so>.<ro><kd>a<ro><so>=<ro><vi>1<ro><so> <ro>" +
+		"<kd>wg<ro><so>.<ro><kd>http<ro><so>.<ro><kd><http-key>method<ro><so>=<ro><vs>\"GET\"<ro>\n" +
+		"<le>|ERR <ro><m>m<ro><so> <ro>" +
+		"<kd><loud-group>wg<ro><so>.<ro><kd>a<ro><so>=<ro><vi><loud>1<ro><so> <ro>" +
+		"<kd><loud-group>wg<ro><so>.<ro><kd><loud-group>http<ro><so>.<ro><kd><http-key>method<ro><so>=<ro><vs><loud>\"GET\"<ro>\n"
+	if buffer.String() != expected {
+		t.Errorf("output \n%q did not match the expected output \n%q", buffer.String(), expected)
+	}
+}
+
+func TestRainbow_Compose(t *testing.T) {
+	base := rainbow.DefaultStyler(&opts)
+	top := rainbow.StylerFunc(func(req rainbow.StyleRequest) rainbow.Style {
+		if req.Element == rainbow.ElementMessage {
+			return rainbow.Style{}.With(rainbow.FlagBold)
+		}
+		return rainbow.Style{}
+	})
+	styler := rainbow.Compose(base, nil, top)
+
+	tests := []struct {
+		Request rainbow.StyleRequest
+		Output  rainbow.AnsiMod
+	}{
+		{Request: rainbow.StyleRequest{Element: rainbow.ElementMessage}, Output: "<m>\x1b[1m"},
+		{Request: rainbow.StyleRequest{Element: rainbow.ElementLevel, Level: slog.LevelWarn}, Output: "<lw>"},
+		{Request: rainbow.StyleRequest{Element: rainbow.ElementKey, Key: "err"}, Output: "<ke>"},
+		{Request: rainbow.StyleRequest{Element: rainbow.ElementGroup, Key: "gr"}, Output: "<tgr>"},
+		{Request: rainbow.StyleRequest{Element: rainbow.ElementValue, Value: slog.DurationValue(time.Second)}, Output: "<vd>"},
+		{Request: rainbow.StyleRequest{Element: rainbow.ElementTime}, Output: "<t>"},
+	}
+	for _, tt := range tests {
+		if out := rainbow.ModOf(styler.Style(tt.Request)); out != tt.Output {
+			t.Errorf("style %q for %+v did not match the expected style %q", out, tt.Request, tt.Output)
+		}
+	}
+}
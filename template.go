@@ -0,0 +1,202 @@
+package rainbow
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+type MessageTemplate int
+
+const (
+	// messages are written as they are
+	MessageTemplateOff MessageTemplate = iota
+	// {key} placeholders are filled in with the values of the attrs,
+	// the attrs used up that way are left out of the attrs
+	MessageTemplateConsume
+	// like MessageTemplateConsume, but the attrs are written as well
+	MessageTemplateKeep
+)
+
+// templateAttr is an attr a placeholder can refer to
+type templateAttr struct {
+	attr slog.Attr
+	// all groups the attr is in, for styling it
+	groups []string
+	// where the attr is, to leave it out once it's used
+	id string
+}
+
+// applyTemplate fills in the placeholders of the message. Consumed
+// attrs are taken out of the record and the context of the state.
+func (h *TextHandler) applyTemplate(r slog.Record, hs *handleState) slog.Record {
+	attrs := h.templateAttrs(r, hs)
+	used := map[string]bool{}
+	msg := h.fillTemplate(r.Message, attrs, used, hs)
+	if h.messageTemplate != MessageTemplateConsume || len(used) == 0 {
+		r.Message = msg
+		return r
+	}
+
+	filtered := slog.NewRecord(r.Time, r.Level, msg, r.PC)
+	i := 0
+	r.Attrs(func(a slog.Attr) bool {
+		if a, ok := unusedAttr(a, fmt.Sprintf("%d.%d", len(hs.Context), i), used); ok {
+			filtered.AddAttrs(a)
+		}
+		i++
+		return true
+	})
+	context := make([]*contextAttrs, 0, len(hs.Context))
+	for c, ca := range hs.Context {
+		var kept []slog.Attr
+		for i, a := range ca.Attrs {
+			if a, ok := unusedAttr(a, fmt.Sprintf("%d.%d", c, i), used); ok {
+				kept = append(kept, a)
+			}
+		}
+		if len(kept) > 0 {
+			context = append(context, &contextAttrs{Groups: ca.Groups, Attrs: kept})
+		}
+	}
+	hs.Context = context
+	hs.Restyle = true
+	return filtered
+}
+
+// templateAttrs names the attrs of a record, by their key and by
+// their dotted path with and without the groups from WithGroup.
+// Record attrs come last, so they win over the ones from WithAttrs.
+func (h *TextHandler) templateAttrs(r slog.Record, hs *handleState) map[string]templateAttr {
+	attrs := map[string]templateAttr{}
+	var visit func(id string, base, inline []string, a slog.Attr)
+	visit = func(id string, base, inline []string, a slog.Attr) {
+		a.Value = a.Value.Resolve()
+		if a.Equal(slog.Attr{}) {
+			return
+		}
+		isGroup := a.Value.Kind() == slog.KindGroup
+		if isGroup && a.Key == "" {
+			for j, c := range a.Value.Group() {
+				visit(id+"."+strconv.Itoa(j), base, inline, c)
+			}
+			return
+		}
+		path := append(inline[:len(inline):len(inline)], a.Key)
+		groups := append(base[:len(base):len(base)], inline...)
+		ta := templateAttr{attr: a, groups: groups, id: id}
+		attrs[strings.Join(path, ".")] = ta
+		if len(base) > 0 {
+			attrs[strings.Join(base, ".")+"."+strings.Join(path, ".")] = ta
+		}
+		if isGroup {
+			for j, c := range a.Value.Group() {
+				visit(id+"."+strconv.Itoa(j), base, path, c)
+			}
+		}
+	}
+	h.eachTopAttr(r, hs, func(ref attrRef, groups []string, a slog.Attr) {
+		visit(fmt.Sprintf("%d.%d", ref.ctx, ref.i), groups, nil, a)
+	})
+	return attrs
+}
+
+// fillTemplate writes the message with its placeholders filled in,
+// {{ and }} for braces. Braces around anything with spaces in it
+// aren't placeholders and stay as they are.
+func (h *TextHandler) fillTemplate(msg string, attrs map[string]templateAttr, used map[string]bool, hs *handleState) string {
+	msgCol := h.style(StyleRequest{Element: ElementMessage, Level: hs.Level}, hs)
+	var buf []byte
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if (c == '{' || c == '}') && i+1 < len(msg) && msg[i+1] == c {
+			buf = append(buf, c)
+			i++
+			continue
+		}
+		end := strings.IndexByte(msg[i:], '}')
+		if c != '{' || end == -1 || !isPlaceholder(msg[i+1:i+end]) {
+			buf = append(buf, c)
+			continue
+		}
+		key := msg[i+1 : i+end]
+		i += end
+		ta, ok := attrs[key]
+		if !ok {
+			// a warning that the key is missing
+			hss := hs.clone()
+			hss.Tint = Style{}
+			col := h.style(StyleRequest{Element: ElementLevel, Level: slog.LevelWarn}, hss)
+			buf = col.AppendSGR(buf)
+			buf = append(append(append(buf, '{'), key...), '}')
+			buf = append(buf, h.resetMod...)
+		} else {
+			used[ta.id] = true
+			buf = h.appendTemplateValue(buf, ta, hs)
+		}
+		// back to the color of the message
+		buf = msgCol.AppendSGR(buf)
+	}
+	return string(buf)
+}
+
+func isPlaceholder(key string) bool {
+	return key != "" && !strings.ContainsAny(key, " \t\n{")
+}
+
+// appendTemplateValue writes a value the way it reads in a sentence,
+// strings without quotes and groups in braces
+func (h *TextHandler) appendTemplateValue(buf []byte, ta templateAttr, hs *handleState) []byte {
+	hss := hs.clone()
+	hss.Groups = ta.groups
+	a := ta.attr
+	switch a.Value.Kind() {
+	case slog.KindString:
+		col := h.style(StyleRequest{Element: ElementValue, Key: a.Key, Groups: ta.groups, Value: a.Value, Level: hs.Level}, hs)
+		target := h.links.ruleTarget(ta.groups, a)
+		if target != "" {
+			buf = appendLinkStart(buf, target)
+		}
+		buf = col.AppendSGR(buf)
+		buf = h.appendHighlighted(buf, a.Value.String(), col, target == "")
+		buf = append(buf, h.resetMod...)
+		if target != "" {
+			buf = appendLinkEnd(buf)
+		}
+		return buf
+	case slog.KindGroup:
+		hss.Inline = true
+		hss.KeyWidth = 0
+		hss.CurrentGroupName = ""
+		hss.Groups = append(ta.groups[:len(ta.groups):len(ta.groups)], a.Key)
+		buf = h.appendSymbol(buf, "{")
+		buf = h.appendAttrs(buf, a.Value.Group(), hss)
+		return h.appendSymbol(buf, "}")
+	}
+	return h.appendValue(buf, a, hss)
+}
+
+// unusedAttr leaves the used attrs out of a, groups left
+// without any attrs are left out along with them
+func unusedAttr(a slog.Attr, id string, used map[string]bool) (slog.Attr, bool) {
+	if used[id] {
+		return slog.Attr{}, false
+	}
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup {
+		return a, true
+	}
+	group := a.Value.Group()
+	kept := make([]slog.Attr, 0, len(group))
+	for j, c := range group {
+		if c, ok := unusedAttr(c, id+"."+strconv.Itoa(j), used); ok {
+			kept = append(kept, c)
+		}
+	}
+	if len(kept) == 0 && len(group) > 0 {
+		return slog.Attr{}, false
+	}
+	a.Value = slog.GroupValue(kept...)
+	return a, true
+}
@@ -0,0 +1,85 @@
+package rainbow_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/nerdwave-nick/rainbow"
+)
+
+func TestRainbow_MessageTemplate(t *testing.T) {
+	tests := []struct {
+		Template rainbow.MessageTemplate
+		Message  string
+		Attrs    []slog.Attr
+		Output   string
+	}{
+		{
+			Template: rainbow.MessageTemplateConsume,
+			Message:  "user {user} bought {count} items",
+			Attrs:    []slog.Attr{slog.String("user", "alice"), slog.Int("count", 3), slog.Bool("paid", true)},
+			Output:   "|INF user alice bought 3 items job.id=7 job.paid=true\n",
+		},
+		{
+			Template: rainbow.MessageTemplateKeep,
+			Message:  "user {user} bought {count} items",
+			Attrs:    []slog.Attr{slog.String("user", "alice"), slog.Int("count", 3)},
+			Output:   "|INF user alice bought 3 items job.id=7 job.user=\"alice\" job.count=3\n",
+		},
+		{
+			Template: rainbow.MessageTemplateOff,
+			Message:  "user {user}",
+			Attrs:    []slog.Attr{slog.String("user", "alice")},
+			Output:   "|INF user {user} job.id=7 job.user=\"alice\"\n",
+		},
+		{
+			// attrs from WithAttrs, with and without the group
+			Template: rainbow.MessageTemplateConsume,
+			Message:  "job {id}, {job.id} again",
+			Output:   "|INF job 7, 7 again\n",
+		},
+		{
+			// into groups, and whole groups
+			Template: rainbow.MessageTemplateConsume,
+			Message:  "{http.method} {http.res}",
+			Attrs:    []slog.Attr{slog.Group("http", slog.String("method", "GET"), slog.String("path", "/"), slog.Group("res", slog.Int("status", 200)))},
+			Output:   "|INF GET {status=200} job.id=7 job.http={path=\"/\"}\n",
+		},
+		{
+			Template: rainbow.MessageTemplateConsume,
+			Message:  "{missing} {a: 1} {{id}} {}",
+			Output:   "|INF {missing} {a: 1} {id} {} job.id=7\n",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("message template test %d", i), func(t *testing.T) {
+			buffer := bytes.NewBuffer(make([]byte, 0))
+			h := rainbow.New(buffer, &rainbow.Options{
+				NoColor:         true,
+				AttrLayout:      rainbow.AttrLayoutSingle,
+				MessageTemplate: tt.Template,
+			}).WithGroup("job").WithAttrs([]slog.Attr{slog.Int("id", 7)})
+			handleNoTime(t, h, slog.LevelInfo, tt.Message, tt.Attrs...)
+			if buffer.String() != tt.Output {
+				t.Errorf("output %q did not match the expected output %q", buffer.String(), tt.Output)
+			}
+		})
+	}
+}
+
+func TestRainbow_MessageTemplateColors(t *testing.T) {
+	buffer := bytes.NewBuffer(make([]byte, 0))
+	h := rainbow.New(buffer, &rainbow.Options{
+		MessageTemplate:  rainbow.MessageTemplateConsume,
+		SpecialOverrides: &rainbow.SpecialColorOverrides{Message: rainbow.Mod(rainbow.Fmt.Bold)},
+	})
+	handleNoTime(t, h, slog.LevelInfo, "paid {paid} {missing}", slog.Bool("paid", true))
+	// values in their value color, missing keys in the warning color
+	expected := "\x1b[34m|INF \x1b[0m\x1b[1mpaid \x1b[32mtrue\x1b[0m\x1b[1m \x1b[33m{missing}\x1b[0m\x1b[1m\x1b[0m\n"
+	if buffer.String() != expected {
+		t.Errorf("output %q did not match the expected output %q", buffer.String(), expected)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package rainbow
+
+import "os"
+
+// terminalWidth is unknown here, $COLUMNS has to do
+func terminalWidth(f *os.File) int {
+	return 0
+}
+
+// resizes can't tell when the terminal gets resized here
+func resizes() uint64 {
+	return 0
+}
@@ -0,0 +1,48 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package rainbow
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+// terminalWidth asks the terminal for its size, 0 if f isn't one
+func terminalWidth(f *os.File) int {
+	var ws struct {
+		Row, Col, Xpixel, Ypixel uint16
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 {
+		return 0
+	}
+	return int(ws.Col)
+}
+
+// resized counts the resizes of the terminal. There is only one
+// channel for the whole process, and no goroutine, whoever asks
+// first after a resize takes the signal from the channel.
+var resized struct {
+	once  sync.Once
+	c     chan os.Signal
+	count atomic.Uint64
+}
+
+// resizes is the number of times the terminal got resized,
+// listening for them starts with the first call
+func resizes() uint64 {
+	resized.once.Do(func() {
+		resized.c = make(chan os.Signal, 1)
+		signal.Notify(resized.c, syscall.SIGWINCH)
+	})
+	select {
+	case <-resized.c:
+		resized.count.Add(1)
+	default:
+	}
+	return resized.count.Load()
+}
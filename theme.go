@@ -0,0 +1,270 @@
+package rainbow
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Theme is a named set of color overrides, that can be kept
+// in a JSON file and applied to Options
+type Theme struct {
+	Name    string                 `json:"name"`
+	Levels  *LevelColorOverrides   `json:"levels,omitempty"`
+	Values  *ValueColorOverrides   `json:"values,omitempty"`
+	Keys    *KeyColorOverrides     `json:"keys,omitempty"`
+	Special *SpecialColorOverrides `json:"special,omitempty"`
+	// Highlight only has an effect with Options.Highlight on
+	Highlight *HighlightColorOverrides `json:"highlight,omitempty"`
+	Symbol    AnsiMod                  `json:"symbol,omitempty"`
+	Reset     AnsiMod                  `json:"reset,omitempty"`
+}
+
+// Apply returns a copy of opts using the colors of the theme,
+// parts the theme leaves out stay as they are in opts
+func (t *Theme) Apply(opts *Options) *Options {
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+	if t.Levels != nil {
+		o.LevelOverrides = t.Levels
+	}
+	if t.Values != nil {
+		o.ValueOverrides = t.Values
+	}
+	if t.Keys != nil {
+		o.KeyOverrides = t.Keys
+	}
+	if t.Special != nil {
+		o.SpecialOverrides = t.Special
+	}
+	if t.Highlight != nil {
+		o.HighlightOverrides = t.Highlight
+	}
+	if t.Symbol != "" {
+		o.SymbolOverride = t.Symbol
+	}
+	if t.Reset != "" {
+		o.ResetOverride = t.Reset
+	}
+	return &o
+}
+
+// LoadTheme reads a theme from JSON, colors are written
+// as attribute names like "bold hi-cyan", see ParseMod
+func LoadTheme(r io.Reader) (*Theme, error) {
+	t := &Theme{}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(t); err != nil {
+		return nil, fmt.Errorf("rainbow: reading theme: %w", err)
+	}
+	return t, nil
+}
+
+// JSON is the theme the way LoadTheme reads it
+func (t *Theme) JSON() string {
+	b, _ := json.MarshalIndent(t, "", "  ")
+	return string(b) + "\n"
+}
+
+// GoConfig is the theme as Go code setting up Options,
+// to be copied into a program
+func (t *Theme) GoConfig() string {
+	var sb strings.Builder
+	sb.WriteString("var opts = &rainbow.Options{\n")
+	writeGoOverrides(&sb, "LevelOverrides", t.Levels)
+	writeGoOverrides(&sb, "ValueOverrides", t.Values)
+	writeGoOverrides(&sb, "KeyOverrides", t.Keys)
+	writeGoOverrides(&sb, "SpecialOverrides", t.Special)
+	writeGoOverrides(&sb, "HighlightOverrides", t.Highlight)
+	if t.Symbol != "" {
+		sb.WriteString("SymbolOverride: " + goMod(t.Symbol) + ",\n")
+	}
+	if t.Reset != "" {
+		sb.WriteString("ResetOverride: " + goMod(t.Reset) + ",\n")
+	}
+	sb.WriteString("}\n")
+	src, err := format.Source([]byte(sb.String()))
+	if err != nil {
+		// can't happen, everything above is valid go
+		return sb.String()
+	}
+	return string(src)
+}
+
+// writeGoOverrides writes one of the *ColorOverrides structs,
+// they only hold mods and maps of mods
+func writeGoOverrides(sb *strings.Builder, field string, overrides any) {
+	v := reflect.ValueOf(overrides)
+	if v.IsNil() {
+		return
+	}
+	v = v.Elem()
+	sb.WriteString(field + ": &rainbow." + v.Type().Name() + "{\n")
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		switch f := v.Field(i).Interface().(type) {
+		case AnsiMod:
+			if f != "" {
+				sb.WriteString(name + ": " + goMod(f) + ",\n")
+			}
+		case map[string]AnsiMod:
+			if len(f) == 0 {
+				continue
+			}
+			keys := make([]string, 0, len(f))
+			for k := range f {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			sb.WriteString(name + ": map[string]rainbow.AnsiMod{\n")
+			for _, k := range keys {
+				sb.WriteString(fmt.Sprintf("%q: %s,\n", k, goMod(f[k])))
+			}
+			sb.WriteString("},\n")
+		}
+	}
+	sb.WriteString("},\n")
+}
+
+// goMod is the Mod call building m, or the plain string
+// for mods using attributes without a name
+func goMod(m AnsiMod) string {
+	attrs, ok := m.attrs()
+	if !ok {
+		return fmt.Sprintf("rainbow.AnsiMod(%q)", string(m))
+	}
+	names := make([]string, 0, len(attrs))
+	for _, a := range attrs {
+		name, ok := attrGoNames[a]
+		if !ok {
+			return fmt.Sprintf("rainbow.AnsiMod(%q)", string(m))
+		}
+		names = append(names, "rainbow."+name)
+	}
+	return "rainbow.Mod(" + strings.Join(names, ", ") + ")"
+}
+
+// Go names of the attributes, like Fg.HiCyan
+var attrGoNames = func() map[AnsiAttr]string {
+	names := map[AnsiAttr]string{}
+	for prefix, set := range map[string]any{"Fmt": Fmt, "Fg": Fg, "Bg": Bg} {
+		v := reflect.ValueOf(set)
+		for i := 0; i < v.NumField(); i++ {
+			names[v.Field(i).Interface().(AnsiAttr)] = prefix + "." + v.Type().Field(i).Name
+		}
+	}
+	return names
+}()
+
+// Themes are the built in themes, "default" first
+func Themes() []*Theme {
+	return []*Theme{
+		{
+			Name:    "default",
+			Levels:  getOrDefaultLevelColorOverrides(nil),
+			Values:  getOrDefaultValueColorOverrides(nil),
+			Keys:    getOrDefaultKeyColorOverrides(nil),
+			Special: getOrDefaultSpecialOverrides(nil),
+		},
+		{
+			Name: "bright",
+			Levels: &LevelColorOverrides{
+				Debug:   Mod(Fmt.Bold, Fg.HiGreen),
+				Info:    Mod(Fmt.Bold, Fg.HiBlue),
+				Warning: Mod(Fmt.Bold, Fg.HiYellow),
+				Error:   Mod(Fmt.Bold, Fg.HiRed),
+			},
+			Values: &ValueColorOverrides{
+				String:   Mod(Fg.HiWhite),
+				Int:      Mod(Fg.HiYellow),
+				Float:    Mod(Fg.HiYellow),
+				Uint:     Mod(Fg.HiYellow),
+				Error:    Mod(Fmt.Bold, Fg.HiRed),
+				Time:     Mod(Fg.HiMagenta),
+				Bool:     Mod(Fg.HiGreen),
+				Duration: Mod(Fg.HiCyan),
+				Any:      Mod(Fg.White),
+				Negative: Mod(Fg.HiMagenta),
+			},
+			Keys: &KeyColorOverrides{
+				Default: Mod(Fg.Cyan),
+				KeyMap: map[string]AnsiMod{
+					"error": Mod(Fmt.Bold, Fg.Red),
+					"err":   Mod(Fmt.Bold, Fg.Red),
+				},
+			},
+			Special: &SpecialColorOverrides{
+				Time:    Mod(Fg.White),
+				Message: Mod(Fmt.Bold),
+			},
+			Symbol: Mod(Fg.HiBlack),
+		},
+		{
+			Name: "ocean",
+			Levels: &LevelColorOverrides{
+				Debug:   Mod(Fg.Cyan),
+				Info:    Mod(Fg.HiBlue),
+				Warning: Mod(Fg.HiMagenta),
+				Error:   Mod(Fmt.Bold, Fg.Magenta),
+			},
+			Values: &ValueColorOverrides{
+				String:   Mod(Fg.HiCyan),
+				Int:      Mod(Fg.Blue),
+				Float:    Mod(Fg.Blue),
+				Uint:     Mod(Fg.Blue),
+				Error:    Mod(Fg.Magenta),
+				Time:     Mod(Fmt.Italic, Fg.Cyan),
+				Bool:     Mod(Fg.HiBlue),
+				Duration: Mod(Fg.Cyan),
+				Any:      Mod(),
+				Negative: Mod(Fg.HiMagenta),
+			},
+			Keys: &KeyColorOverrides{
+				Default: Mod(Fmt.Faint, Fg.Cyan),
+			},
+			Special: &SpecialColorOverrides{
+				Time:    Mod(Fmt.Faint, Fg.Blue),
+				Message: Mod(Fg.HiWhite),
+			},
+			Symbol: Mod(Fmt.Faint, Fg.Blue),
+		},
+		{
+			Name: "mono",
+			Levels: &LevelColorOverrides{
+				Debug:   Mod(Fmt.Faint),
+				Info:    Mod(),
+				Warning: Mod(Fmt.Bold),
+				Error:   Mod(Fmt.Bold, Fmt.Underline),
+			},
+			Values: &ValueColorOverrides{
+				Error: Mod(Fmt.Bold),
+				Time:  Mod(Fmt.Italic),
+			},
+			Keys: &KeyColorOverrides{
+				Default: Mod(Fmt.Faint),
+			},
+			Special: &SpecialColorOverrides{
+				Time:    Mod(Fmt.Faint),
+				Message: Mod(Fmt.Bold),
+			},
+			Symbol: Mod(Fmt.Faint),
+		},
+	}
+}
+
+// ThemeByName finds a built in theme, ignoring case
+func ThemeByName(name string) (*Theme, bool) {
+	for _, t := range Themes() {
+		if strings.EqualFold(t.Name, name) {
+			return t, true
+		}
+	}
+	return nil, false
+}
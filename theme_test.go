@@ -0,0 +1,69 @@
+package rainbow_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/nerdwave-nick/rainbow"
+)
+
+func TestRainbow_ThemeJSON(t *testing.T) {
+	for _, theme := range rainbow.Themes() {
+		t.Run(theme.Name, func(t *testing.T) {
+			loaded, err := rainbow.LoadTheme(strings.NewReader(theme.JSON()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// same options, same output
+			expected := bytes.NewBuffer(make([]byte, 0))
+			actual := bytes.NewBuffer(make([]byte, 0))
+			handleNoTime(t, rainbow.New(expected, theme.Apply(nil)), slog.LevelError, "m", slog.Any("err", 1), slog.Int("n", -1))
+			handleNoTime(t, rainbow.New(actual, loaded.Apply(nil)), slog.LevelError, "m", slog.Any("err", 1), slog.Int("n", -1))
+			if actual.String() != expected.String() {
+				t.Errorf("output %q of the loaded theme did not match the expected output %q", actual, expected)
+			}
+		})
+	}
+}
+
+func TestRainbow_LoadTheme(t *testing.T) {
+	theme, err := rainbow.LoadTheme(strings.NewReader(`{
+		"name": "mine",
+		"levels": {"error": "bold red", "info": "hi-blue"},
+		"keys": {"keyMap": {"user": "\u001b[38;5;208m"}},
+		"symbol": "faint"
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if theme.Levels.Error != rainbow.Mod(rainbow.Fmt.Bold, rainbow.Fg.Red) || theme.Keys.KeyMap["user"] != "\x1b[38;5;208m" {
+		t.Errorf("theme %+v was not loaded as expected", theme)
+	}
+	opts := theme.Apply(&rainbow.Options{NoColor: true, SymbolOverride: "<so>", ResetOverride: "<ro>"})
+	if !opts.NoColor || opts.SymbolOverride != rainbow.Mod(rainbow.Fmt.Faint) || opts.ResetOverride != "<ro>" || opts.ValueOverrides != nil {
+		t.Errorf("applying the theme gave %+v", opts)
+	}
+
+	expected := `var opts = &rainbow.Options{
+	LevelOverrides: &rainbow.LevelColorOverrides{
+		Error: rainbow.Mod(rainbow.Fmt.Bold, rainbow.Fg.Red),
+		Info:  rainbow.Mod(rainbow.Fg.HiBlue),
+	},
+	KeyOverrides: &rainbow.KeyColorOverrides{
+		KeyMap: map[string]rainbow.AnsiMod{
+			"user": rainbow.AnsiMod("\x1b[38;5;208m"),
+		},
+	},
+	SymbolOverride: rainbow.Mod(rainbow.Fmt.Faint),
+}
+`
+	if actual := theme.GoConfig(); actual != expected {
+		t.Errorf("go config \n%s did not match the expected \n%s", actual, expected)
+	}
+
+	if _, err := rainbow.LoadTheme(strings.NewReader(`{"levels": {"error": "very red"}}`)); err == nil {
+		t.Errorf("expected an error for an unknown attribute")
+	}
+}
@@ -0,0 +1,163 @@
+package rainbow
+
+import (
+	"log/slog"
+)
+
+type GroupStyle int
+
+const (
+	// group names as dotted prefixes of the keys, like http.request.method=
+	GroupStyleDotted GroupStyle = iota
+	// groups as a tree with their name written once, children
+	// below it drawn with box-drawing guides
+	GroupStyleTree
+	// GroupStyleTree with plain ASCII guides, for terminals
+	// and fonts without the box-drawing characters
+	GroupStyleTreeASCII
+)
+
+type treeGuides struct {
+	// in front of a child with more children after it, and of the last one
+	branch, last string
+	// below a child with more children after it, and below the last one
+	cont, space string
+}
+
+var (
+	boxGuides   = treeGuides{branch: "├─ ", last: "└─ ", cont: "│  ", space: "   "}
+	asciiGuides = treeGuides{branch: "|- ", last: "`- ", cont: "|  ", space: "   "}
+)
+
+// treeNode is an attr, or a group of them
+type treeNode struct {
+	attr slog.Attr
+	// groups the node is in
+	groups   []string
+	group    bool
+	children []*treeNode
+}
+
+// attrTree gathers the attrs from WithAttrs and the record into
+// a single tree, the groups from WithGroup merged with their attrs
+func (h *TextHandler) attrTree(r slog.Record, hs *handleState) *treeNode {
+	root := &treeNode{group: true}
+	for _, ca := range hs.Context {
+		root.at(ca.Groups).add(ca.Attrs...)
+	}
+	n := root.at(hs.Groups)
+	r.Attrs(func(a slog.Attr) bool {
+		a.Value = a.Value.Resolve()
+		n.add(a)
+		return true
+	})
+	return root
+}
+
+// at finds the node of a WithGroup path, the groups only ever get
+// deeper so it's always the last child
+func (n *treeNode) at(groups []string) *treeNode {
+	for i, name := range groups {
+		var next *treeNode
+		if len(n.children) > 0 {
+			if c := n.children[len(n.children)-1]; c.group && c.attr.Key == name {
+				next = c
+			}
+		}
+		if next == nil {
+			next = &treeNode{attr: slog.Attr{Key: name}, groups: groups[:i:i], group: true}
+			n.children = append(n.children, next)
+		}
+		n = next
+	}
+	return n
+}
+
+func (n *treeNode) add(attrs ...slog.Attr) {
+	path := n.path()
+	for _, a := range attrs {
+		if a.Equal(slog.Attr{}) {
+			continue
+		}
+		if a.Value.Kind() != slog.KindGroup {
+			n.children = append(n.children, &treeNode{attr: a, groups: path})
+			continue
+		}
+		if a.Key == "" {
+			// inlined into the parent, like slog does
+			n.add(a.Value.Group()...)
+			continue
+		}
+		g := &treeNode{attr: slog.Attr{Key: a.Key}, groups: path, group: true}
+		g.add(a.Value.Group()...)
+		n.children = append(n.children, g)
+	}
+}
+
+// path are the groups the children of n are in
+func (n *treeNode) path() []string {
+	if n.attr.Key == "" {
+		return n.groups
+	}
+	path := append(n.groups[:len(n.groups):len(n.groups)], n.attr.Key)
+	return path[:len(path):len(path)]
+}
+
+// empty groups are left out, like slog does
+func (n *treeNode) empty() bool {
+	if !n.group {
+		return false
+	}
+	for _, c := range n.children {
+		if !c.empty() {
+			return false
+		}
+	}
+	return true
+}
+
+// appendTree writes the children of n one per line, with guides
+// in front of everything below the top level
+func (h *TextHandler) appendTree(buf []byte, n *treeNode, guide string, first *bool, hs *handleState) []byte {
+	guides := boxGuides
+	if h.groupStyle == GroupStyleTreeASCII {
+		guides = asciiGuides
+	}
+	children := make([]*treeNode, 0, len(n.children))
+	for _, c := range n.children {
+		if !c.empty() {
+			children = append(children, c)
+		}
+	}
+	for i, c := range children {
+		if !*first {
+			buf = append(buf, h.attrSeparator(hs)...)
+		}
+		*first = false
+
+		childGuide := ""
+		if n.attr.Key != "" {
+			branch, cont := guides.branch, guides.cont
+			if i == len(children)-1 {
+				branch, cont = guides.last, guides.space
+			}
+			buf = append(buf, h.symbolMod...)
+			buf = append(buf, guide...)
+			buf = append(buf, branch...)
+			buf = append(buf, h.resetMod...)
+			childGuide = guide + cont
+		}
+
+		if !c.group {
+			hss := hs.clone()
+			hss.CurrentGroupName = ""
+			hss.Groups = c.groups
+			buf = h.appendAttr(buf, c.attr, hss)
+			continue
+		}
+		col := h.style(StyleRequest{Element: ElementGroup, Key: c.attr.Key, Groups: c.groups, Level: hs.Level}, hs)
+		buf = h.appendStyled(buf, col, c.attr.Key)
+		buf = h.appendTree(buf, c, childGuide, first, hs)
+	}
+	return buf
+}
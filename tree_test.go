@@ -0,0 +1,94 @@
+package rainbow_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/nerdwave-nick/rainbow"
+)
+
+func TestRainbow_GroupTree(t *testing.T) {
+	attrs := []slog.Attr{
+		slog.Group("http", slog.String("method", "GET"), slog.Group("res", slog.Int("status", 200), slog.Group("empty"))),
+		slog.Int("n", 1),
+	}
+	tests := []struct {
+		GroupStyle rainbow.GroupStyle
+		NoColor    bool
+		Output     string
+	}{
+		{
+			GroupStyle: rainbow.GroupStyleTree,
+			NoColor:    true,
+			Output: "|INF m\n" +
+				"\ta=1\n" +
+				"\tjob\n" +
+				"\t├─ id=7\n" +
+				"\t├─ http\n" +
+				"\t│  ├─ method=\"GET\"\n" +
+				"\t│  └─ res\n" +
+				"\t│     └─ status=200\n" +
+				"\t└─ n=1\n",
+		},
+		{
+			GroupStyle: rainbow.GroupStyleTreeASCII,
+			NoColor:    true,
+			Output: "|INF m\n" +
+				"\ta=1\n" +
+				"\tjob\n" +
+				"\t|- id=7\n" +
+				"\t|- http\n" +
+				"\t|  |- method=\"GET\"\n" +
+				"\t|  `- res\n" +
+				"\t|     `- status=200\n" +
+				"\t`- n=1\n",
+		},
+		{
+			// guides in the symbol color, groups in theirs
+			GroupStyle: rainbow.GroupStyleTree,
+			Output: "<li>|INF <ro><m>m<ro><so><mas><ro>" +
+				"<kd>a<ro><so>=<ro><vi>1<ro><so><aas><ro>" +
+				"<kd>job<ro><so><aas><ro>" +
+				"<so>├─ <ro><kd>id<ro><so>=<ro><vi>7<ro><so><aas><ro>" +
+				"<so>├─ <ro><kd>http<ro><so><aas><ro>" +
+				"<so>│  ├─ <ro><kd>method<ro><so>=<ro><vs>\"GET\"<ro><so><aas><ro>" +
+				"<so>│  └─ <ro><kd>res<ro><so><aas><ro>" +
+				"<so>│     └─ <ro><kd>status<ro><so>=<ro><vi>200<ro><so><aas><ro>" +
+				"<so>└─ <ro><kd>n<ro><so>=<ro><vi>1<ro>\n",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("group tree test %d", i), func(t *testing.T) {
+			buffer := bytes.NewBuffer(make([]byte, 0))
+			opts := opts
+			opts.NoColor = tt.NoColor
+			opts.GroupStyle = tt.GroupStyle
+			if tt.NoColor {
+				opts.MessageAttrSeparator = ""
+				opts.AttrAttrSeparator = ""
+			}
+			h := rainbow.New(buffer, &opts).
+				WithAttrs([]slog.Attr{slog.Int("a", 1)}).
+				WithGroup("job").
+				WithAttrs([]slog.Attr{slog.Int("id", 7)})
+			handleNoTime(t, h, slog.LevelInfo, "m", attrs...)
+			if buffer.String() != tt.Output {
+				t.Errorf("output %q did not match the expected output %q", buffer.String(), tt.Output)
+			}
+		})
+	}
+}
+
+func TestRainbow_GroupTreeInline(t *testing.T) {
+	// single lines keep their braces
+	buffer := bytes.NewBuffer(make([]byte, 0))
+	h := rainbow.New(buffer, &rainbow.Options{NoColor: true, GroupStyle: rainbow.GroupStyleTree, AttrLayout: rainbow.AttrLayoutSingle})
+	handleNoTime(t, h.WithGroup("job"), slog.LevelInfo, "m", slog.Group("http", slog.Int("status", 200)))
+	expected := "|INF m job.http={status=200}\n"
+	if buffer.String() != expected {
+		t.Errorf("output %q did not match the expected output %q", buffer.String(), expected)
+	}
+}